@@ -1,21 +1,32 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/J-Mihir/go-bookstore/pkg/routes"
-	"github.com/gorilla/mux"
+	"github.com/J-Mihir/go-bookstore/pkg/app"
+	"github.com/J-Mihir/go-bookstore/pkg/config"
 )
 
 func main() {
-	r := mux.NewRouter()
-	routes.RegisterBookStoreRoutes(r)
-	routes.RegisterUserRoutes(r)
-	routes.RegisterTransactionRoutes(r)
-	routes.RegisterCategoryRoutes(r)
-	routes.RegisterAuthRoutes(r)
-	http.Handle("/", r)
-	log.Println("Server running at http://localhost:9010")
-	log.Fatal(http.ListenAndServe(":9010", r))
+	cfg, _, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	a, err := app.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Cancel the context on SIGINT or SIGTERM so Run can drain requests and close the database.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
 }
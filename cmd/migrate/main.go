@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/migrations"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
+)
+
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    revert the last n applied migrations (default 1)
+  status      list migrations and whether they have been applied
+  create-admin <name> <email> <membership_id>
+              create a user with the admin role, e.g. the first one of a new
+              library; the password is read from ADMIN_PASSWORD or stdin
+
+flags are the same as the server's (--config, --db-driver, --db-dsn, ...)`
+
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(args) < 1 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	db, err := config.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("database: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(db)
+		for _, m := range ran {
+			log.Printf("applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(ran) == 0 {
+			log.Println("database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatalf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			log.Printf("reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrations.List(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	case "create-admin":
+		if len(args) != 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		user := &models.User{
+			Name:         args[1],
+			Email:        args[2],
+			MembershipID: args[3],
+			Password:     adminPassword(),
+			Role:         models.AdminRole,
+		}
+		if problems := validate.Fields(user); len(problems) > 0 {
+			for _, p := range problems {
+				log.Printf("%s: %s", p.Field, p.Message)
+			}
+			os.Exit(1)
+		}
+		// The password hashing is handled by the BeforeSave hook in the User model.
+		if err := repository.NewGormStore(db).Users().Create(user); err != nil {
+			log.Fatalf("creating admin: %v", err)
+		}
+		log.Printf("created admin %s with user ID %d", user.Email, user.ID)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+// adminPassword returns ADMIN_PASSWORD, or else the first line of stdin, so
+// the password stays out of the shell history.
+func adminPassword() string {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("reading password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
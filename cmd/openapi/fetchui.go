@@ -0,0 +1,102 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+)
+
+// registry is the npm registry swagger-ui-dist is downloaded from.
+const registry = "https://registry.npmjs.org/swagger-ui-dist/"
+
+var client = &http.Client{Timeout: time.Minute}
+
+// fetchSwaggerUI downloads swagger-ui-dist at openapi.SwaggerUIVersion, checks
+// the tarball against the integrity hash the registry publishes for it, and
+// writes openapi.SwaggerUIFiles and the license to dir.
+func fetchSwaggerUI(dir string) error {
+	var meta struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	}
+	body, err := get(registry + openapi.SwaggerUIVersion)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return fmt.Errorf("reading package metadata: %w", err)
+	}
+
+	tarball, err := get(meta.Dist.Tarball)
+	if err != nil {
+		return err
+	}
+	sum := sha512.Sum512(tarball)
+	if got := "sha512-" + base64.StdEncoding.EncodeToString(sum[:]); got != meta.Dist.Integrity {
+		return fmt.Errorf("%s: integrity %s, want %s", meta.Dist.Tarball, got, meta.Dist.Integrity)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return err
+	}
+	wanted := append([]string{"LICENSE"}, openapi.SwaggerUIFiles...)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := cutPackageDir(header.Name)
+		if !ok || !slices.Contains(wanted, name) {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
+		}
+		wanted = slices.DeleteFunc(wanted, func(w string) bool { return w == name })
+	}
+	if len(wanted) > 0 {
+		return fmt.Errorf("%s has no %v", meta.Dist.Tarball, wanted)
+	}
+	return nil
+}
+
+// cutPackageDir strips the package/ directory npm tarballs put every file in.
+func cutPackageDir(name string) (string, bool) {
+	return strings.CutPrefix(name, "package/")
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
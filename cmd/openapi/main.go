@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+	"github.com/J-Mihir/go-bookstore/pkg/routes"
+	"github.com/gorilla/mux"
+)
+
+const usage = `usage: openapi <command>
+
+commands:
+  check   verify every route declares who may call it, then compare
+          pkg/openapi/openapi.json with the registered routes and the Go
+          request and response types; exits 1 on any problem
+  print   write the OpenAPI document to stdout
+  fetch-ui [dir]
+          download the Swagger UI files served at /docs into dir
+          (default pkg/openapi/swaggerui); run by go generate ./pkg/openapi`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "check":
+		// The document describes v1; its paths are relative to the server URL /api/v1.
+		// Only the routes are compared, so the handlers need no dependencies.
+		router := mux.NewRouter()
+		if err := routes.V1.Register(router, &controllers.Handler{}, &middleware.Auth{}); err != nil {
+			log.Fatal(err)
+		}
+		if err := openapi.Check(router, controllers.Schemas, routes.V1.Bodies()); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("openapi.json matches the API")
+	case "print":
+		os.Stdout.Write(openapi.Document())
+	case "fetch-ui":
+		dir := filepath.Join("pkg", "openapi", "swaggerui")
+		if len(os.Args) > 2 {
+			dir = os.Args[2]
+		}
+		if err := fetchSwaggerUI(dir); err != nil {
+			log.Fatalf("fetching Swagger UI %s: %v", openapi.SwaggerUIVersion, err)
+		}
+		log.Printf("wrote Swagger UI %s to %s", openapi.SwaggerUIVersion, dir)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,162 @@
+// Package apierror defines the JSON error envelope returned by every endpoint:
+//
+//	{"error": {"code": "not_found", "message": "Book not found", "details": [...], "request_id": "..."}}
+//
+// Codes are stable identifiers clients can switch on; messages are for people
+// and may change. Internal errors are logged with the request ID and never
+// sent to the client.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// HeaderRequestID carries the request ID on requests and responses.
+const HeaderRequestID = "X-Request-ID"
+
+// Error codes. New codes may be added; existing ones never change meaning.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidQuery         = "invalid_query"
+	CodeInvalidBody          = "invalid_body"
+	CodeUnknownField         = "unknown_field"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidToken         = "invalid_token"
+	CodeTokenReused          = "refresh_token_reused"
+	CodeInvalidLogin         = "invalid_credentials"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeDuplicate            = "duplicate"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeInvalidReference     = "invalid_reference"
+	CodeInternal             = "internal_error"
+
+	CodeBorrowLimit       = "borrow_limit_reached"
+	CodeBookUnavailable   = "book_unavailable"
+	CodeCopyUnavailable   = "copy_unavailable"
+	CodeCopyInCirculation = "copy_in_circulation"
+	CodeInvalidTransition = "invalid_status_transition"
+	CodeAlreadyReturned   = "already_returned"
+	CodeLoanLost          = "loan_lost"
+	CodeNotReservable     = "not_reservable"
+	CodeAlreadyReserved   = "already_reserved"
+	CodeCategoryInUse     = "category_in_use"
+	CodeBulkAborted       = "bulk_aborted"
+	CodeRoleInUse         = "role_in_use"
+	CodeRoleProtected     = "role_protected"
+	CodeLastAdmin         = "last_admin"
+)
+
+// FieldError describes a problem with one field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an API error response.
+type Error struct {
+	Status    int          `json:"-"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+
+	// cause is logged for internal errors and never sent to the client.
+	cause error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.cause }
+
+// New returns an error with the given HTTP status, code and message.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithDetails returns a copy of e carrying the given field errors.
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	c := *e
+	c.Details = append(append([]FieldError{}, e.Details...), details...)
+	return &c
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// Validation reports invalid fields with 422 Unprocessable Entity.
+func Validation(details ...FieldError) *Error {
+	return New(http.StatusUnprocessableEntity, CodeValidation, "The request contains invalid fields").WithDetails(details...)
+}
+
+// Internal hides cause behind a generic 500 response; cause is logged when written.
+func Internal(cause error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "An internal error occurred", cause: cause}
+}
+
+// From maps err onto an API error. An *Error is returned as is, the
+// repository's sentinel errors get their matching status, and anything else
+// is an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, repository.ErrNotFound):
+		return NotFound("Record not found")
+	case errors.Is(err, repository.ErrDuplicate):
+		return Conflict(CodeDuplicate, "A record with the same unique value already exists")
+	case errors.Is(err, repository.ErrInvalidReference):
+		return New(http.StatusUnprocessableEntity, CodeInvalidReference, "The request refers to a record that does not exist")
+	case errors.Is(err, repository.ErrInvalidQuery):
+		return New(http.StatusBadRequest, CodeInvalidQuery, err.Error())
+	default:
+		return Internal(err)
+	}
+}
+
+// Write sends e as the JSON error envelope. The request ID is taken from the
+// response's X-Request-ID header, which the RequestID middleware sets.
+func Write(w http.ResponseWriter, e *Error) {
+	body := *e
+	body.RequestID = w.Header().Get(HeaderRequestID)
+	if e.cause != nil {
+		log.Printf("request %s: %d %s: %v", body.RequestID, e.Status, e.Code, e.cause)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(struct {
+		Error *Error `json:"error"`
+	}{&body})
+}
+
+// Respond writes err as the JSON error envelope, mapping it with From.
+func Respond(w http.ResponseWriter, err error) {
+	Write(w, From(err))
+}
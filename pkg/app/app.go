@@ -0,0 +1,230 @@
+// Package app assembles the BookHive server from its configuration: it opens
+// the database, applies migrations, builds the repositories and wires the
+// controllers, middleware and routes. Nothing is connected at import time.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/mail"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/migrations"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/routes"
+	"github.com/J-Mihir/go-bookstore/pkg/search"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// App is a fully wired BookHive server.
+type App struct {
+	Config *config.Config
+	// DB is nil when the App was built with NewWithStore.
+	DB     *gorm.DB
+	Store  repository.Store
+	Router *mux.Router
+}
+
+// New connects to the configured database, applies pending migrations when
+// auto_migrate is enabled, and builds the HTTP router on top of it.
+func New(cfg *config.Config) (*App, error) {
+	db, err := config.Open(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.AutoMigrate {
+		ran, err := migrations.Up(db)
+		if err != nil {
+			closeDB(db)
+			return nil, err
+		}
+		for _, m := range ran {
+			log.Printf("applied migration %04d_%s", m.Version, m.Name)
+		}
+	}
+
+	searcher, err := search.ForDatabase(db, cfg.Search)
+	if err != nil {
+		closeDB(db)
+		return nil, err
+	}
+
+	a, err := newApp(cfg, repository.NewGormStore(db), searcher)
+	if err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	a.DB = db
+	return a, nil
+}
+
+// NewWithStore builds the App on an existing store without touching a database,
+// e.g. repository.NewMemoryStore() in tests. Catalog search scans the store.
+func NewWithStore(cfg *config.Config, store repository.Store) (*App, error) {
+	return newApp(cfg, store, search.NewScanBackend(store))
+}
+
+// newApp wires the controllers and routes. It fails if the mail sender is
+// misconfigured or a route does not declare who may call it.
+func newApp(cfg *config.Config, store repository.Store, searcher search.Backend) (*App, error) {
+	mailer, err := mail.FromConfig(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	h := controllers.New(store, searcher, mailer, cfg)
+	auth := &middleware.Auth{
+		SigningKey:  []byte(cfg.Auth.JWTSecret),
+		Revoked:     h.TokenRevoked,
+		Permissions: h.RolePermissions,
+	}
+
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		apierror.Write(w, apierror.NotFound("No such endpoint"))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		apierror.Write(w, apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed for this endpoint"))
+	})
+	if err := routes.Mount(r, h, auth, routes.Versions...); err != nil {
+		return nil, err
+	}
+	if cfg.API.LegacyRoutes {
+		if err := routes.MountLegacy(r, h, auth, routes.V1, cfg.API.LegacyDeprecated, cfg.API.LegacySunset); err != nil {
+			return nil, err
+		}
+	}
+
+	return &App{Config: cfg, Store: store, Router: r}, nil
+}
+
+// Handler returns the root HTTP handler. Every request gets a request ID,
+// bodies are capped at server.max_body_bytes, and panics become JSON error responses.
+func (a *App) Handler() http.Handler {
+	return middleware.RequestID(middleware.Recover(middleware.LimitBody(a.Config.Server.MaxBodyBytes)(a.Router)))
+}
+
+// Server returns an http.Server for the App with the configured limits.
+func (a *App) Server() *http.Server {
+	s := a.Config.Server
+	return &http.Server{
+		Addr:              s.Addr,
+		Handler:           a.Handler(),
+		ReadTimeout:       s.ReadTimeout,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+	}
+}
+
+// Run serves HTTP on the configured address until ctx is cancelled. It then
+// stops accepting connections, waits up to server.shutdown_timeout for in-flight
+// requests (such as borrows and returns) to finish, and closes the database.
+func (a *App) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.Config.Server.Addr)
+	if err != nil {
+		a.Close()
+		return err
+	}
+	return a.Serve(ctx, ln)
+}
+
+// Serve is Run on an existing listener.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	srv := a.Server()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+	log.Printf("Server running at %s (%s)", ln.Addr(), a.Config.Env)
+
+	purgeCtx, stopPurging := context.WithCancel(ctx)
+	defer stopPurging()
+	go a.purgeTrashPeriodically(purgeCtx)
+
+	select {
+	case err := <-serveErr:
+		stopPurging()
+		a.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down: draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("Shutdown timeout reached; closing remaining connections")
+		srv.Close()
+	}
+	if closeErr := a.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	log.Println("Server stopped")
+	return err
+}
+
+// Close releases the database connection pool, if the App owns one.
+func (a *App) Close() error {
+	if a.DB == nil {
+		return nil
+	}
+	return closeDB(a.DB)
+}
+
+// purgeTrashPeriodically permanently removes records past the trash retention
+// period, and expired refresh and password reset tokens, every
+// trash.purge_interval until ctx is cancelled.
+func (a *App) purgeTrashPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(a.Config.Trash.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			result, err := repository.PurgeTrash(a.Store, now.Add(-a.Config.Trash.Retention))
+			if err != nil {
+				log.Printf("purging trash: %v", err)
+				continue
+			}
+			if result.Books+result.Users+result.Categories > 0 {
+				log.Printf("purged trash: %d books, %d users, %d categories", result.Books, result.Users, result.Categories)
+			}
+			if n, err := a.Store.RefreshTokens().PurgeExpiredBefore(now); err != nil {
+				log.Printf("purging refresh tokens: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d expired refresh tokens", n)
+			}
+			if n, err := a.Store.PasswordResets().PurgeExpiredBefore(now); err != nil {
+				log.Printf("purging password reset tokens: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d expired password reset tokens", n)
+			}
+		}
+	}
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("closing database: %w", err)
+	}
+	return sqlDB.Close()
+}
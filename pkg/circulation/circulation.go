@@ -0,0 +1,259 @@
+// Package circulation implements book checkout, checkin and lost loans. Each
+// operation runs in a single database transaction with the affected rows
+// locked, so concurrent requests cannot double-lend a book or exceed a user's
+// borrow limit.
+package circulation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+const (
+	MaxLoans   = 5   // Business Rule: A user can borrow a maximum of 5 books
+	LoanPeriod = 14  // days until a loan is due
+	FinePerDay = 1.0 // charged for every full day a loan is overdue
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrBookNotFound        = errors.New("book not found")
+	ErrBookUnavailable     = errors.New("book is currently not available")
+	ErrCopyNotFound        = errors.New("copy not found")
+	ErrCopyUnavailable     = errors.New("copy is currently not available")
+	ErrBorrowLimit         = errors.New("borrow limit reached")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrAlreadyReturned     = errors.New("book has already been returned")
+	ErrLoanLost            = errors.New("loan was closed as lost")
+)
+
+// Service performs circulation operations against a repository store.
+type Service struct {
+	store repository.Store
+	now   func() time.Time
+}
+
+func NewService(store repository.Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Checkout lends a copy of the book to the user and records the loan. If the
+// user has a fulfilled reservation for the book, the copy held for them is
+// lent; otherwise barcode selects a specific copy, or the first copy on the
+// shelf is used when barcode is empty.
+func (s *Service) Checkout(userID, bookID uint, barcode string) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.store.Atomic(func(tx repository.Store) error {
+		// Lock the user first so concurrent checkouts for the same user
+		// see each other's loans when counting against the limit, then the
+		// book so concurrent checkouts of the same title pick distinct copies.
+		if _, err := tx.Users().FindForUpdate(userID); err != nil {
+			return notFound(err, ErrUserNotFound)
+		}
+		if _, err := tx.Books().FindForUpdate(bookID); err != nil {
+			return notFound(err, ErrBookNotFound)
+		}
+
+		active, err := tx.Transactions().CountActiveByUser(userID)
+		if err != nil {
+			return err
+		}
+		if active >= MaxLoans {
+			return ErrBorrowLimit
+		}
+
+		bookCopy, err := s.copyToLend(tx, userID, bookID, barcode)
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		transaction = &models.Transaction{
+			UserID:     userID,
+			BookID:     bookID,
+			CopyID:     bookCopy.ID,
+			BorrowDate: now,
+			DueDate:    now.AddDate(0, 0, LoanPeriod),
+		}
+		if err := tx.Transactions().Create(transaction); err != nil {
+			return err
+		}
+
+		if err := bookCopy.TransitionTo(models.CopyBorrowed); err != nil {
+			return err
+		}
+		return tx.Copies().Update(bookCopy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// copyToLend picks the copy for a checkout. A copy held for the user's
+// reservation takes priority and completes that reservation.
+func (s *Service) copyToLend(tx repository.Store, userID, bookID uint, barcode string) (*models.BookCopy, error) {
+	held, err := tx.Reservations().FindHeld(userID, bookID)
+	switch {
+	case err == nil:
+		bookCopy, err := tx.Copies().FindForUpdate(*held.CopyID)
+		if err != nil {
+			return nil, notFound(err, ErrCopyNotFound)
+		}
+		held.Status = "Collected"
+		if err := tx.Reservations().Update(held); err != nil {
+			return nil, err
+		}
+		return bookCopy, nil
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	if barcode == "" {
+		bookCopy, err := tx.Copies().FirstAvailable(bookID)
+		return bookCopy, notFound(err, ErrBookUnavailable)
+	}
+
+	bookCopy, err := tx.Copies().FindByBarcode(barcode)
+	if err != nil {
+		return nil, notFound(err, ErrCopyNotFound)
+	}
+	if bookCopy.BookID != bookID {
+		return nil, ErrCopyNotFound
+	}
+	if bookCopy, err = tx.Copies().FindForUpdate(bookCopy.ID); err != nil {
+		return nil, notFound(err, ErrCopyNotFound)
+	}
+	if bookCopy.Status != models.CopyAvailable {
+		return nil, ErrCopyUnavailable
+	}
+	return bookCopy, nil
+}
+
+// Checkin closes the loan, charges any overdue fine and holds the returned copy
+// for the oldest pending reservation, if there is one.
+func (s *Service) Checkin(transactionID uint) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.store.Atomic(func(tx repository.Store) error {
+		var err error
+		transaction, err = tx.Transactions().FindForUpdate(transactionID)
+		if err != nil {
+			return notFound(err, ErrTransactionNotFound)
+		}
+		if err := checkOpen(transaction); err != nil {
+			return err
+		}
+
+		// Lock the book as Checkout does, so the returned copy and the
+		// reservation queue are not raced by a concurrent checkout.
+		if _, err := tx.Books().FindForUpdate(transaction.BookID); err != nil {
+			return notFound(err, ErrBookNotFound)
+		}
+		bookCopy, err := tx.Copies().FindForUpdate(transaction.CopyID)
+		if err != nil {
+			return notFound(err, ErrCopyNotFound)
+		}
+
+		returnDate := s.now()
+		transaction.ReturnDate = &returnDate
+		transaction.Fine = overdueFine(transaction, returnDate)
+		if err := tx.Transactions().Update(transaction); err != nil {
+			return err
+		}
+
+		reservation, err := tx.Reservations().OldestPendingForBook(transaction.BookID)
+		switch {
+		case err == nil:
+			// A reservation was found, so fulfill it and hold the copy for that user.
+			reservation.Status = "Fulfilled"
+			reservation.CopyID = &bookCopy.ID
+			if err := tx.Reservations().Update(reservation); err != nil {
+				return err
+			}
+			err = bookCopy.TransitionTo(models.CopyReserved)
+		case errors.Is(err, repository.ErrNotFound):
+			err = bookCopy.TransitionTo(models.CopyAvailable)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Copies().Update(bookCopy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// MarkLost closes the loan because the borrower lost the copy. The copy
+// becomes Lost, the loan no longer counts toward the borrow limit, and the
+// overdue fine up to now is charged. A copy that turns up again can be put
+// back on the shelf by setting it Available.
+func (s *Service) MarkLost(transactionID uint) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.store.Atomic(func(tx repository.Store) error {
+		var err error
+		transaction, err = tx.Transactions().FindForUpdate(transactionID)
+		if err != nil {
+			return notFound(err, ErrTransactionNotFound)
+		}
+		if err := checkOpen(transaction); err != nil {
+			return err
+		}
+
+		if _, err := tx.Books().FindForUpdate(transaction.BookID); err != nil {
+			return notFound(err, ErrBookNotFound)
+		}
+		bookCopy, err := tx.Copies().FindForUpdate(transaction.CopyID)
+		if err != nil {
+			return notFound(err, ErrCopyNotFound)
+		}
+
+		lostAt := s.now()
+		transaction.LostAt = &lostAt
+		transaction.Fine = overdueFine(transaction, lostAt)
+		if err := tx.Transactions().Update(transaction); err != nil {
+			return err
+		}
+
+		if err := bookCopy.TransitionTo(models.CopyLost); err != nil {
+			return err
+		}
+		return tx.Copies().Update(bookCopy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// checkOpen reports why a loan can no longer be returned or marked lost.
+func checkOpen(transaction *models.Transaction) error {
+	switch {
+	case transaction.ReturnDate != nil:
+		return ErrAlreadyReturned
+	case transaction.LostAt != nil:
+		return ErrLoanLost
+	}
+	return nil
+}
+
+// overdueFine is the fine for every full day the loan was overdue at t.
+func overdueFine(transaction *models.Transaction, t time.Time) float64 {
+	if !t.After(transaction.DueDate) {
+		return 0
+	}
+	daysOverdue := int(t.Sub(transaction.DueDate).Hours() / 24)
+	return float64(daysOverdue) * FinePerDay
+}
+
+// notFound replaces repository.ErrNotFound with a domain-specific error.
+func notFound(err, replacement error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return replacement
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package circulation_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/circulation"
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/migrations"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// eachStore runs test against the memory store and a migrated SQLite
+// database, so the rules are checked through the GORM queries too. Both
+// serialise whole transactions; the row locks taken for MySQL and PostgreSQL
+// are not exercised here.
+func eachStore(t *testing.T, test func(t *testing.T, store repository.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := config.Open(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrations.Up(db); err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		test(t, repository.NewGormStore(db))
+	})
+}
+
+func newUser(t *testing.T, store repository.Store, n int) *models.User {
+	t.Helper()
+	user := &models.User{
+		Name:         fmt.Sprintf("Reader %d", n),
+		Email:        fmt.Sprintf("reader%d@example.com", n),
+		MembershipID: fmt.Sprintf("M%03d", n),
+		Role:         models.DefaultRole,
+	}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// newBook creates a book with the given number of copies on the shelf.
+func newBook(t *testing.T, store repository.Store, n, copies int) *models.Book {
+	t.Helper()
+	book := &models.Book{Name: fmt.Sprintf("Book %d", n), ISBN: fmt.Sprintf("isbn-%d", n), CategoryID: 1}
+	if err := store.Books().Create(book); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < copies; i++ {
+		bookCopy := &models.BookCopy{BookID: book.ID, Barcode: fmt.Sprintf("B%d-%d", n, i), Status: models.CopyAvailable}
+		if err := store.Copies().Create(bookCopy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return book
+}
+
+// checkoutAll runs the checkouts concurrently and returns their errors.
+func checkoutAll(service *circulation.Service, userIDs, bookIDs []uint) []error {
+	errs := make([]error, len(userIDs))
+	var wg sync.WaitGroup
+	for i := range userIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = service.Checkout(userIDs[i], bookIDs[i], "")
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrentCheckoutsLendTheLastCopyOnce(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		book := newBook(t, store, 1, 1)
+		var userIDs, bookIDs []uint
+		for i := 0; i < 10; i++ {
+			userIDs = append(userIDs, newUser(t, store, i).ID)
+			bookIDs = append(bookIDs, book.ID)
+		}
+
+		lent := 0
+		for _, err := range checkoutAll(circulation.NewService(store), userIDs, bookIDs) {
+			switch {
+			case err == nil:
+				lent++
+			case !errors.Is(err, circulation.ErrBookUnavailable):
+				t.Errorf("Checkout() = %v, want nil or %v", err, circulation.ErrBookUnavailable)
+			}
+		}
+		if lent != 1 {
+			t.Errorf("the only copy was lent %d times", lent)
+		}
+		if _, err := store.Copies().FirstAvailable(book.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FirstAvailable() = %v, want the copy to be on loan", err)
+		}
+	})
+}
+
+func TestConcurrentCheckoutsRespectTheBorrowLimit(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		user := newUser(t, store, 1)
+		var userIDs, bookIDs []uint
+		for i := 0; i < circulation.MaxLoans+5; i++ {
+			userIDs = append(userIDs, user.ID)
+			bookIDs = append(bookIDs, newBook(t, store, i, 1).ID)
+		}
+
+		lent := 0
+		for _, err := range checkoutAll(circulation.NewService(store), userIDs, bookIDs) {
+			switch {
+			case err == nil:
+				lent++
+			case !errors.Is(err, circulation.ErrBorrowLimit):
+				t.Errorf("Checkout() = %v, want nil or %v", err, circulation.ErrBorrowLimit)
+			}
+		}
+		if lent != circulation.MaxLoans {
+			t.Errorf("%d checkouts succeeded, want %d", lent, circulation.MaxLoans)
+		}
+		active, err := store.Transactions().CountActiveByUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != int64(circulation.MaxLoans) {
+			t.Errorf("CountActiveByUser() = %d, want %d", active, circulation.MaxLoans)
+		}
+	})
+}
+
+func TestLostLoanFreesTheBorrowLimit(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		service := circulation.NewService(store)
+		user := newUser(t, store, 1)
+		var first *models.Transaction
+		for i := 0; i < circulation.MaxLoans; i++ {
+			loan, err := service.Checkout(user.ID, newBook(t, store, i, 1).ID, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first == nil {
+				first = loan
+			}
+		}
+		extra := newBook(t, store, circulation.MaxLoans, 1)
+		if _, err := service.Checkout(user.ID, extra.ID, ""); !errors.Is(err, circulation.ErrBorrowLimit) {
+			t.Fatalf("Checkout() over the limit = %v, want %v", err, circulation.ErrBorrowLimit)
+		}
+
+		if _, err := service.MarkLost(first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.Checkin(first.ID); !errors.Is(err, circulation.ErrLoanLost) {
+			t.Errorf("Checkin() of a lost loan = %v, want %v", err, circulation.ErrLoanLost)
+		}
+		if _, err := service.Checkout(user.ID, extra.ID, ""); err != nil {
+			t.Errorf("Checkout() after a loan was lost = %v, want nil", err)
+		}
+	})
+}
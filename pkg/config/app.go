@@ -1,24 +1,97 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-var (
-	db *gorm.DB
+// Supported values for DatabaseConfig.Driver.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
-func Connect() {
-	dsn := "mihir:mihir@tcp(127.0.0.1:3306)/simplerest?charset=utf8mb4&parseTime=True&loc=Local"
+// defaultMySQLDSN keeps the original development database working when no DSN is configured.
+const defaultMySQLDSN = "mihir:mihir@tcp(127.0.0.1:3306)/simplerest?charset=utf8mb4&parseTime=True&loc=Local"
+
+// defaultSQLiteDSN is the embedded database file used when the sqlite driver has no DSN.
+const defaultSQLiteDSN = "bookhive.db"
+
+// sqliteParams make SQLite transactions take the write lock up front and wait
+// for it, so concurrent borrow/return transactions queue instead of failing
+// with "database is locked".
+var sqliteParams = []string{"_txlock=immediate", "_busy_timeout=5000"}
 
-	d, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// Open connects to the configured database (mysql, postgres or sqlite).
+// An empty DSN falls back to the driver's development default.
+func Open(cfg DatabaseConfig) (*gorm.DB, error) {
+	driver, dsn := cfg.Driver, cfg.DSN
+
+	dialector, err := dialectorFor(driver, dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	db = d
+
+	d, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		// Repositories report missing rows as repository.ErrNotFound; they are not worth logging.
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s database: %w", driver, err)
+	}
+	return d, nil
 }
 
-func GetDB() *gorm.DB {
-	return db
+func dialectorFor(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverMySQL:
+		if dsn == "" {
+			dsn = defaultMySQLDSN
+		}
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		if dsn == "" {
+			return nil, fmt.Errorf("a DSN is required for the postgres driver")
+		}
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		return sqlite.Open(withSQLiteParams(dsn)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (expected mysql, postgres or sqlite)", driver)
+	}
+}
+
+// withSQLiteParams appends each of sqliteParams the DSN does not already set.
+func withSQLiteParams(dsn string) string {
+	for _, param := range sqliteParams {
+		name := param[:strings.Index(param, "=")+1]
+		if strings.Contains(dsn, name) {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + param
+		} else {
+			dsn += "?" + param
+		}
+	}
+	return dsn
 }
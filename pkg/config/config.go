@@ -0,0 +1,396 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported values for Config.Env.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Supported values for SearchConfig.Backend.
+const (
+	SearchAuto     = "auto"
+	SearchSQL      = "sql"
+	SearchFullText = "fulltext"
+	SearchScan     = "scan"
+)
+
+// Supported values for MailConfig.Sender.
+const (
+	MailLog  = "log"
+	MailFile = "file"
+	MailSMTP = "smtp"
+)
+
+// InsecureJWTSecret is used in development when no secret is configured.
+// Validation rejects it in production.
+const InsecureJWTSecret = "default_insecure_secret_key"
+
+// minProductionSecretLength is the shortest JWT secret accepted in production.
+const minProductionSecretLength = 32
+
+// Config is the complete application configuration.
+type Config struct {
+	Env      string         `yaml:"env"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Trash    TrashConfig    `yaml:"trash"`
+	Search   SearchConfig   `yaml:"search"`
+	API      APIConfig      `yaml:"api"`
+	Mail     MailConfig     `yaml:"mail"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	// MaxBodyBytes is the largest request body the API accepts.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// ShutdownTimeout bounds how long in-flight requests may take to drain on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	JWTSecret string `yaml:"jwt_secret"`
+	// TokenTTL is the lifetime of an access token.
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	// RefreshTokenTTL is how long a refresh token can be exchanged for new
+	// tokens; each refresh starts a new period.
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	// PasswordResetTTL is how long a password reset token can be used.
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+	// PasswordResetURL is the page that completes a reset; the emailed link
+	// adds ?token=. Without it the email contains the bare token.
+	PasswordResetURL string `yaml:"password_reset_url"`
+}
+
+// TrashConfig controls how long soft-deleted records stay restorable.
+type TrashConfig struct {
+	// Retention is how long a deleted book, user or category can be restored before it is purged.
+	Retention time.Duration `yaml:"retention"`
+	// PurgeInterval is how often the server purges records past their retention.
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+// SearchConfig selects how GET /books/search is answered.
+type SearchConfig struct {
+	// Backend is auto (FULLTEXT on MySQL, SQL elsewhere), sql, fulltext or scan.
+	Backend string `yaml:"backend"`
+}
+
+// APIConfig controls the unversioned paths the API was served at before /api/v1.
+type APIConfig struct {
+	// LegacyRoutes also serves the v1 routes at their old paths, e.g. /books.
+	LegacyRoutes bool `yaml:"legacy_routes"`
+	// LegacyDeprecated is announced in the Deprecation header of legacy responses.
+	LegacyDeprecated time.Time `yaml:"legacy_deprecated"`
+	// LegacySunset is announced in the Sunset header: the date after which the
+	// legacy paths may be removed.
+	LegacySunset time.Time `yaml:"legacy_sunset"`
+}
+
+// MailConfig selects how email, such as password reset links, is sent.
+type MailConfig struct {
+	// Sender is log (write messages to the server log), file (append them to
+	// File) or smtp. log and file are meant for local use.
+	Sender string `yaml:"sender"`
+	From   string `yaml:"from"`
+	File   string `yaml:"file"`
+	// SMTPAddr is the host:port of the SMTP server; the credentials are optional.
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+}
+
+// Default returns the configuration used before any file, environment variable or flag is applied.
+func Default() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Addr:              ":9010",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:      DriverMySQL,
+			AutoMigrate: true,
+		},
+		Auth: AuthConfig{
+			TokenTTL:         15 * time.Minute,
+			RefreshTokenTTL:  30 * 24 * time.Hour,
+			PasswordResetTTL: time.Hour,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Search: SearchConfig{
+			Backend: SearchAuto,
+		},
+		API: APIConfig{
+			LegacyRoutes:     true,
+			LegacyDeprecated: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+			LegacySunset:     time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Mail: MailConfig{
+			Sender: MailLog,
+			From:   "BookHive <no-reply@localhost>",
+		},
+	}
+}
+
+// Load builds the configuration in increasing order of precedence: defaults,
+// the YAML file named by --config or BOOKHIVE_CONFIG, environment variables,
+// and command-line flags. It returns the positional arguments left after the flags.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("bookhive", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("BOOKHIVE_CONFIG"), "path to a YAML configuration file")
+	env := fs.String("env", "", "runtime environment (development or production)")
+	addr := fs.String("addr", "", "address the HTTP server listens on")
+	driver := fs.String("db-driver", "", "database driver (mysql, postgres or sqlite)")
+	dsn := fs.String("db-dsn", "", "database connection string")
+	autoMigrate := fs.Bool("auto-migrate", cfg.Database.AutoMigrate, "apply pending migrations on startup")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+
+	// Only flags that were given on the command line override earlier sources.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "env":
+			cfg.Env = *env
+		case "addr":
+			cfg.Server.Addr = *addr
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-dsn":
+			cfg.Database.DSN = *dsn
+		case "auto-migrate":
+			cfg.Database.AutoMigrate = *autoMigrate
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if v, ok := os.LookupEnv("BOOKHIVE_ENV"); ok {
+		c.Env = v
+	}
+	if v, ok := os.LookupEnv("BOOKHIVE_ADDR"); ok {
+		c.Server.Addr = v
+	}
+	if v, ok := os.LookupEnv("DB_DRIVER"); ok {
+		c.Database.Driver = v
+	}
+	if v, ok := os.LookupEnv("DB_DSN"); ok {
+		c.Database.DSN = v
+	}
+	if v, ok := os.LookupEnv("DB_AUTO_MIGRATE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("DB_AUTO_MIGRATE: %w", err)
+		}
+		c.Database.AutoMigrate = b
+	}
+	if v, ok := os.LookupEnv("SEARCH_BACKEND"); ok {
+		c.Search.Backend = v
+	}
+	if v, ok := os.LookupEnv("API_LEGACY_ROUTES"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("API_LEGACY_ROUTES: %w", err)
+		}
+		c.API.LegacyRoutes = b
+	}
+	if v, ok := os.LookupEnv("JWT_SECRET_KEY"); ok {
+		c.Auth.JWTSecret = v
+	}
+	if v, ok := os.LookupEnv("JWT_TOKEN_TTL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("JWT_TOKEN_TTL: %w", err)
+		}
+		c.Auth.TokenTTL = d
+	}
+	if v, ok := os.LookupEnv("JWT_REFRESH_TOKEN_TTL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("JWT_REFRESH_TOKEN_TTL: %w", err)
+		}
+		c.Auth.RefreshTokenTTL = d
+	}
+	if v, ok := os.LookupEnv("MAIL_SENDER"); ok {
+		c.Mail.Sender = v
+	}
+	if v, ok := os.LookupEnv("MAIL_SMTP_PASSWORD"); ok {
+		c.Mail.SMTPPassword = v
+	}
+	return nil
+}
+
+// Validate normalises the configuration and reports every invalid setting.
+// In development an unset JWT secret falls back to InsecureJWTSecret;
+// production refuses to start with it.
+func (c *Config) Validate() error {
+	c.Env = strings.ToLower(strings.TrimSpace(c.Env))
+	c.Database.Driver = strings.ToLower(strings.TrimSpace(c.Database.Driver))
+	c.Search.Backend = strings.ToLower(strings.TrimSpace(c.Search.Backend))
+	c.Mail.Sender = strings.ToLower(strings.TrimSpace(c.Mail.Sender))
+
+	var problems []string
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		problems = append(problems, fmt.Sprintf("env must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr is required")
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"trash.retention", c.Trash.Retention},
+		{"trash.purge_interval", c.Trash.PurgeInterval},
+	}
+	for _, t := range durations {
+		if t.value <= 0 {
+			problems = append(problems, t.name+" must be positive")
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		problems = append(problems, "server.max_header_bytes must be positive")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems = append(problems, "server.max_body_bytes must be positive")
+	}
+	switch c.Database.Driver {
+	case DriverMySQL, DriverSQLite:
+	case DriverPostgres:
+		if c.Database.DSN == "" {
+			problems = append(problems, "database.dsn is required for the postgres driver")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("database.driver must be mysql, postgres or sqlite, got %q", c.Database.Driver))
+	}
+	switch c.Search.Backend {
+	case SearchAuto, SearchSQL, SearchScan:
+	case SearchFullText:
+		if c.Database.Driver != DriverMySQL {
+			problems = append(problems, "search.backend fulltext requires the mysql driver")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("search.backend must be auto, sql, fulltext or scan, got %q", c.Search.Backend))
+	}
+	if c.Auth.TokenTTL <= 0 {
+		problems = append(problems, "auth.token_ttl must be positive")
+	}
+	if c.Auth.RefreshTokenTTL <= c.Auth.TokenTTL {
+		problems = append(problems, "auth.refresh_token_ttl must be longer than auth.token_ttl")
+	}
+	if c.API.LegacyRoutes && !c.API.LegacySunset.After(c.API.LegacyDeprecated) {
+		problems = append(problems, "api.legacy_sunset must be after api.legacy_deprecated")
+	}
+	if c.Auth.PasswordResetTTL <= 0 {
+		problems = append(problems, "auth.password_reset_ttl must be positive")
+	}
+	if c.Auth.PasswordResetURL != "" {
+		if u, err := url.Parse(c.Auth.PasswordResetURL); err != nil || !u.IsAbs() {
+			problems = append(problems, "auth.password_reset_url must be an absolute URL")
+		}
+	}
+	if c.Mail.From == "" {
+		problems = append(problems, "mail.from is required")
+	}
+	switch c.Mail.Sender {
+	case MailLog:
+	case MailFile:
+		if c.Mail.File == "" {
+			problems = append(problems, "mail.file is required for the file sender")
+		}
+	case MailSMTP:
+		if c.Mail.SMTPAddr == "" {
+			problems = append(problems, "mail.smtp_addr is required for the smtp sender")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("mail.sender must be log, file or smtp, got %q", c.Mail.Sender))
+	}
+
+	if c.Env == EnvProduction {
+		switch {
+		case c.Auth.JWTSecret == "" || c.Auth.JWTSecret == InsecureJWTSecret:
+			problems = append(problems, "auth.jwt_secret must be set to a private value in production")
+		case len(c.Auth.JWTSecret) < minProductionSecretLength:
+			problems = append(problems, fmt.Sprintf("auth.jwt_secret must be at least %d characters in production", minProductionSecretLength))
+		}
+		if c.Mail.Sender == MailLog {
+			problems = append(problems, "mail.sender log would write password reset tokens to the server log; use file or smtp in production")
+		}
+	} else if c.Auth.JWTSecret == "" {
+		log.Println("WARNING: JWT secret not set. Using an insecure development default.")
+		c.Auth.JWTSecret = InsecureJWTSecret
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// caller returns the claims of the authenticated user making the request.
+// The route must be wrapped in middleware.Auth.JWTMiddleware.
+func caller(r *http.Request) (*middleware.Claims, error) {
+	claims, err := middleware.ClaimsFrom(r.Context())
+	if err != nil {
+		return nil, apierror.Internal(err)
+	}
+	return claims, nil
+}
+
+// require fails with 403 unless the caller's role grants perm. The role is
+// read through s, so inside store.Atomic pass the transaction.
+func require(s repository.Store, claims *middleware.Claims, perm, action string) error {
+	granted, err := rolePermissions(s, claims.Role)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(granted, perm) {
+		return apierror.Forbidden(fmt.Sprintf("%s requires the %s permission", action, perm))
+	}
+	return nil
+}
+
+// authorizeUser lets everyone act on themselves, and callers granted perm on
+// any user.
+func (h *Handler) authorizeUser(r *http.Request, userID uint, perm string) error {
+	claims, err := caller(r)
+	if err != nil {
+		return err
+	}
+	if claims.UserID == userID {
+		return nil
+	}
+	return require(h.store, claims, perm, "Accessing another user's account")
+}
+
+// actingUser returns the user a request acts for: the caller, or with
+// manage the requested user. requested is zero when the request names no user.
+func (h *Handler) actingUser(r *http.Request, requested uint, manage string) (uint, error) {
+	claims, err := caller(r)
+	if err != nil {
+		return 0, err
+	}
+	if requested == 0 || requested == claims.UserID {
+		return claims.UserID, nil
+	}
+	if err := require(h.store, claims, manage, "Acting for another user"); err != nil {
+		return 0, err
+	}
+	return requested, nil
+}
+
+// RolePermissions returns the permissions the named role grants; an unknown
+// role grants none. The middleware uses it for every permission check.
+func (h *Handler) RolePermissions(role string) ([]string, error) {
+	return rolePermissions(h.store, role)
+}
+
+func rolePermissions(s repository.Store, role string) ([]string, error) {
+	found, err := s.Roles().FindByName(role)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return found.Permissions, nil
+}
+
+// checkRoleAssignment checks that a user may be given the named role: it
+// must exist, and a role granting more than self-service permissions may
+// only be assigned by callers with roles:manage. claims is nil for
+// self-registration.
+func checkRoleAssignment(s repository.Store, claims *middleware.Claims, name string) error {
+	role, err := s.Roles().FindByName(name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.Validation(apierror.FieldError{Field: "role", Code: "not_found", Message: "role does not exist"})
+	}
+	if err != nil {
+		return err
+	}
+	if selfService(role) {
+		return nil
+	}
+	if claims == nil {
+		return apierror.Forbidden(fmt.Sprintf("The %s role cannot be chosen at registration", name))
+	}
+	return require(s, claims, models.PermRolesManage, fmt.Sprintf("Assigning the %s role", name))
+}
+
+// checkOutranks lets the caller change another user's profile only if that
+// user's role is self-service, or the caller has roles:manage or every
+// permission of that role. Otherwise a librarian could change an admin's
+// email and take the account over with a password reset.
+func checkOutranks(s repository.Store, claims *middleware.Claims, target *models.User) error {
+	if claims.UserID == target.ID {
+		return nil
+	}
+	role, err := s.Roles().FindByName(target.Role)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil // an unknown role grants nothing
+	}
+	if err != nil {
+		return err
+	}
+	if selfService(role) {
+		return nil
+	}
+	granted, err := rolePermissions(s, claims.Role)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(granted, models.PermRolesManage) {
+		return nil
+	}
+	for _, p := range role.Permissions {
+		if !slices.Contains(granted, p) {
+			return apierror.Forbidden(fmt.Sprintf("Changing a user with the %s role requires the %s permission or every permission of that role", role.Name, models.PermRolesManage))
+		}
+	}
+	return nil
+}
+
+// selfService reports whether role only lets its users act for themselves.
+func selfService(role *models.Role) bool {
+	for _, p := range role.Permissions {
+		if !slices.Contains(models.SelfServicePermissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// errLastAdmin rejects deleting or demoting the only remaining admin, which
+// would leave nobody able to manage roles.
+var errLastAdmin = apierror.Conflict(apierror.CodeLastAdmin, "Cannot delete or demote the last admin")
+
+// checkAdminsRemain fails with errLastAdmin unless another admin remains
+// besides the one being deleted or demoted. It locks the admin role, so
+// concurrent removals of two admins are counted one after the other.
+func checkAdminsRemain(tx repository.Store) error {
+	role, err := tx.Roles().FindByName(models.AdminRole)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Roles().FindForUpdate(role.ID); err != nil {
+		return err
+	}
+	_, admins, err := tx.Users().List(repository.ListQuery{Filters: map[string]string{"role": models.AdminRole}, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return errLastAdmin
+	}
+	return nil
+}
@@ -1,45 +1,56 @@
 package controllers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"fmt" // Import the fmt package for printing
+	"errors"
+	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/J-Mihir/go-bookstore/pkg/middleware" // Import middleware to use its Claims struct
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtKey []byte
+// errInvalidCredentials is the same for an unknown email and a wrong password,
+// so logins cannot be used to discover accounts.
+var errInvalidCredentials = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidLogin, "Invalid credentials")
 
-// We'll use an init function to set the key and add our diagnostic print statement.
-func init() {
-	secret := os.Getenv("JWT_SECRET_KEY")
-	// --- DIAGNOSTIC LOG ---
-	// This will print the key your application is using to the terminal on startup.
-	fmt.Printf("INFO: JWT_SECRET_KEY being used: '%s'\n", secret)
-	if secret == "" {
-		fmt.Println("WARNING: JWT_SECRET_KEY environment variable not set. Using a default, insecure key.")
-		secret = "default_insecure_secret_key" // Fallback for safety, but shows an error state
+// RegisterUser handles new user registration. Anyone can register with a
+// self-service role such as student; other roles are assigned by users with
+// roles:manage. The first admin is created with "migrate create-admin".
+func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
+	var req UserRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-	jwtKey = []byte(secret)
-}
-
-// RegisterUser handles new user registration.
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	user := req.user()
+	if user.Role == "" {
+		user.Role = models.DefaultRole
+	}
+	if err := checkRoleAssignment(h.store, nil, user.Role); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
 	// The password hashing is handled by the BeforeSave hook in the User model.
-	if _, err := user.CreateUser(); err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusConflict)
+	if err := h.store.Users().Create(user); err != nil {
+		apierror.Respond(w, duplicateUser(err))
 		return
 	}
 
@@ -51,50 +62,227 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// LoginRequest is the body of POST /login.
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// TokenResponse carries a signed access token and the refresh token that
+// replaces it when it expires.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of Token in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// RefreshRequest is the body of POST /token/refresh and POST /logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// errInvalidRefreshToken covers unknown, expired and revoked refresh tokens.
+var errInvalidRefreshToken = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired refresh token")
+
 // LoginUser handles user authentication and token generation.
-func LoginUser(w http.ResponseWriter, r *http.Request) {
-	var creds struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
+	var creds LoginRequest
+	if err := utils.ParseBody(r, &creds); err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validate.Struct(&creds); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	db := models.GetDB()
-	var user models.User
-	if result := db.Where("email = ?", creds.Email).First(&user); result.Error != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	user, err := h.store.Users().FindByEmail(creds.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(w, errInvalidCredentials)
+		return
+	}
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
 	// Compare the stored hashed password with the one provided in the request
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password)); err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		apierror.Write(w, errInvalidCredentials)
+		return
+	}
+
+	// Start a new session: a refresh token and the first access token.
+	var tokens TokenResponse
+	err = h.store.Atomic(func(tx repository.Store) error {
+		tokens, err = h.issueTokens(tx, user, randomToken(16))
+		return err
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. The old refresh token is used up: presenting it again means
+// it was copied, so the whole session is revoked.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	var tokens TokenResponse
+	var reused *models.RefreshToken
+	err := h.store.Atomic(func(tx repository.Store) error {
+		current, err := tx.RefreshTokens().FindByHashForUpdate(hashToken(req.RefreshToken))
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch {
+		case current.RevokedAt != nil || !now.Before(current.ExpiresAt):
+			return errInvalidRefreshToken
+		case current.UsedAt != nil:
+			// Return nil so the revocation is committed.
+			reused = current
+			return tx.RefreshTokens().RevokeSession(current.SessionID, now)
+		}
+
+		user, err := tx.Users().FindByID(current.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		current.UsedAt = &now
+		if err := tx.RefreshTokens().Update(current); err != nil {
+			return err
+		}
+		tokens, err = h.issueTokens(tx, user, current.SessionID)
+		return err
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if reused != nil {
+		log.Printf("request %s: refresh token reused; revoked session %s of user %d",
+			w.Header().Get(apierror.HeaderRequestID), reused.SessionID, reused.UserID)
+		apierror.Write(w, apierror.New(http.StatusUnauthorized, apierror.CodeTokenReused,
+			"Refresh token has already been used; the session has been revoked, log in again"))
 		return
 	}
 
-	// Create the JWT claims, using the struct from the middleware package
-	expirationTime := time.Now().Add(24 * time.Hour)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout ends the session of the given refresh token. Its access tokens stop
+// working at once and its refresh tokens can no longer be used.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	err := h.store.Atomic(func(tx repository.Store) error {
+		token, err := tx.RefreshTokens().FindByHashForUpdate(hashToken(req.RefreshToken))
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		return tx.RefreshTokens().RevokeSession(token.SessionID, time.Now())
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TokenRevoked reports whether the session an access token belongs to has
+// ended: logged out, revoked or expired. JWTMiddleware calls it on every
+// authenticated request.
+func (h *Handler) TokenRevoked(claims *middleware.Claims) (bool, error) {
+	if claims.ID == "" {
+		// Issued before sessions existed.
+		return true, nil
+	}
+	active, err := h.store.RefreshTokens().SessionActive(claims.ID, time.Now())
+	return !active, err
+}
+
+// issueTokens stores a new refresh token for the session and signs an access
+// token whose jti is the session ID. tx must come from store.Atomic.
+func (h *Handler) issueTokens(tx repository.Store, user *models.User, sessionID string) (TokenResponse, error) {
+	now := time.Now()
+	refresh := randomToken(32)
+	err := tx.RefreshTokens().Create(&models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(h.auth.RefreshTokenTTL),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
 	claims := &middleware.Claims{
 		UserID: user.ID,
 		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        sessionID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.auth.TokenTTL)),
 		},
 	}
-
-	// Create the token with the claims and sign it with our secret
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.auth.JWTSecret))
 	if err != nil {
-		http.Error(w, "Failed to create token", http.StatusInternalServerError)
-		return
+		return TokenResponse{}, err
 	}
+	return TokenResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(h.auth.TokenTTL / time.Second),
+	}, nil
+}
 
-	// Finally, send the token to the client
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+// randomToken returns n random bytes, URL-safe base64 encoded.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is the form in which refresh tokens are stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,107 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/gorilla/mux"
+)
+
+// call sends body to handler and decodes the response into out, if given.
+func call(t *testing.T, handler http.HandlerFunc, vars map[string]string, body string, out any) int {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body, err)
+		}
+	}
+	return w.Code
+}
+
+// errorResponse is the body of an API error.
+type errorResponse struct {
+	Error apierror.Error `json:"error"`
+}
+
+func TestRegisterRejectsRecordFields(t *testing.T) {
+	store := repository.NewMemoryStore()
+	h := controllers.New(store, nil, nil, &config.Config{})
+	admin := &models.User{Name: "Admin", Email: "admin@example.com", Password: "password", MembershipID: "A1", Role: models.AdminRole}
+	if err := store.Users().Create(admin); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{`"ID":1`, `"id":1`, `"DeletedAt":"2020-01-01T00:00:00Z"`, `"CreatedAt":"2020-01-01T00:00:00Z"`} {
+		body := `{"name":"Eve","email":"eve@example.com","password":"password","membership_id":"M1",` + field + `}`
+		var resp errorResponse
+		if code := call(t, h.RegisterUser, nil, body, &resp); code != http.StatusBadRequest || resp.Error.Code != apierror.CodeUnknownField {
+			t.Errorf("register with %s = %d %s, want 400 %s", field, code, resp.Error.Code, apierror.CodeUnknownField)
+		}
+	}
+	if _, err := store.Users().FindByEmail("eve@example.com"); err == nil {
+		t.Error("a rejected registration created a user")
+	}
+	if found, err := store.Users().FindByID(admin.ID); err != nil || found.Email != admin.Email {
+		t.Errorf("FindByID(%d) = %v, %v; want the admin unchanged", admin.ID, found, err)
+	}
+
+	var user models.User
+	body := `{"name":"Eve","email":"eve@example.com","password":"password","membership_id":"M1"}`
+	if code := call(t, h.RegisterUser, nil, body, &user); code != http.StatusCreated {
+		t.Fatalf("register = %d, want 201", code)
+	}
+	if user.ID == admin.ID || user.DeletedAt.Valid || user.Role != models.DefaultRole {
+		t.Errorf("registered user %d with role %q, deleted %v; want a new live %s", user.ID, user.Role, user.DeletedAt.Valid, models.DefaultRole)
+	}
+}
+
+func TestCreateRejectsRecordFields(t *testing.T) {
+	store := repository.NewMemoryStore()
+	h := controllers.New(store, nil, nil, &config.Config{})
+	category := &models.Category{Name: "Fiction"}
+	if err := store.Categories().Create(category); err != nil {
+		t.Fatal(err)
+	}
+	book := &models.Book{Name: "Dune", ISBN: "9780441013593", CategoryID: category.ID}
+	if err := store.Books().Create(book); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		vars    map[string]string
+		body    string // FIELD is replaced with a record field
+	}{
+		{"category", h.CreateCategory, nil, `{"name":"History",FIELD}`},
+		{"book", h.CreateBook, nil, `{"name":"Emma","isbn":"0-306-40615-2","category_id":1,FIELD}`},
+		{"bulk book", h.BulkBooks, nil, `{"operations":[{"op":"create","book":{"name":"Emma","isbn":"0-306-40615-2","category_id":1,FIELD}}]}`},
+		{"copy", h.CreateBookCopy, map[string]string{"bookId": "1"}, `{"barcode":"X1",FIELD}`},
+		{"role", h.CreateRole, nil, `{"name":"volunteer",FIELD}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, field := range []string{`"ID":1`, `"DeletedAt":"2020-01-01T00:00:00Z"`} {
+				body := strings.Replace(tt.body, "FIELD", field, 1)
+				var resp errorResponse
+				if code := call(t, tt.handler, tt.vars, body, &resp); code != http.StatusBadRequest || resp.Error.Code != apierror.CodeUnknownField {
+					t.Errorf("create with %s = %d %s, want 400 %s", field, code, resp.Error.Code, apierror.CodeUnknownField)
+				}
+			}
+		})
+	}
+}
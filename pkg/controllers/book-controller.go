@@ -2,144 +2,313 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
 	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 	"github.com/gorilla/mux"
 )
 
-// GetBook retrieves all books
-func GetBook(w http.ResponseWriter, r *http.Request) {
-	newBooks := models.GetAllBooks()
-	res, _ := json.Marshal(newBooks)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+// GetBook retrieves a page of books, optionally filtered and sorted
+func (h *Handler) GetBook(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, models.Book{}, "category")
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	q, p, err := parseListQuery(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	newBooks, total, err := h.store.Books().List(q)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if v.include["category"] {
+		books := make([]*models.Book, len(newBooks))
+		for i := range newBooks {
+			books[i] = &newBooks[i]
+		}
+		if err := h.includeCategories(books...); err != nil {
+			apierror.Respond(w, err)
+			return
+		}
+	}
+	writeList(w, r, v, newBooks, total, p)
 }
 
 // GetBookById retrieves a single book by its ID
-func GetBookById(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetBookById(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	bookId := vars["bookId"]
 	ID, err := strconv.ParseInt(bookId, 10, 64)
 	if err != nil {
-		fmt.Println("error while parsing")
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
 	}
-	bookDetails, _ := models.GetBookById(ID)
-	res, _ := json.Marshal(bookDetails)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+	v, err := parseView(r, models.Book{}, "category")
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	bookDetails, err := h.store.Books().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Book not found"))
+		return
+	}
+	if v.include["category"] {
+		if err := h.includeCategories(bookDetails); err != nil {
+			apierror.Respond(w, err)
+			return
+		}
+	}
+	writeResource(w, r, v, bookDetails)
 }
 
-// CreateBook adds a new book to the database
-func CreateBook(w http.ResponseWriter, r *http.Request) {
-	createBook := &models.Book{}
-	utils.ParseBody(r, createBook)
+// BookRequest is the body of POST /books and the book of a bulk operation:
+// the fields of a book a client may set.
+type BookRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Author      string `json:"author"`
+	Publication string `json:"publication"`
+	ISBN        string `json:"isbn" validate:"required,isbn"`
+	Genre       string `json:"genre"`
+	Edition     string `json:"edition"`
+	CategoryID  uint   `json:"category_id" validate:"required"`
+	Copies      int    `json:"copies" validate:"min=0"`
+}
 
-	// Check for required fields to prevent creating an empty book.
-	if createBook.Name == "" || createBook.ISBN == "" || createBook.CategoryID == 0 {
-		http.Error(w, "Missing required fields: name, isbn, and category_id are required", http.StatusBadRequest)
-		return
+func (req *BookRequest) book() *models.Book {
+	return &models.Book{
+		Name:        req.Name,
+		Author:      req.Author,
+		Publication: req.Publication,
+		ISBN:        req.ISBN,
+		Genre:       req.Genre,
+		Edition:     req.Edition,
+		CategoryID:  req.CategoryID,
+		Copies:      req.Copies,
 	}
+}
 
-	// Set availability based on the number of copies
-	if createBook.Copies > 0 {
-		createBook.Availability = "Available"
-	} else {
-		createBook.Availability = "Not Available"
+// CreateBook adds a new book to the database
+func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
+	req := &BookRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-
-	// Validate the Category ID to ensure it exists
-	var category models.Category
-	db := models.GetDB()
-	result := db.First(&category, createBook.CategoryID)
-	if result.Error != nil || result.RowsAffected == 0 {
-		http.Error(w, "Invalid Category ID provided", http.StatusBadRequest)
+	newBook := req.book()
+	err := h.store.Atomic(func(tx repository.Store) error {
+		return createBook(tx, newBook)
+	})
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	b, err := createBook.CreateBook()
+	book, err := h.store.Books().FindByID(newBook.ID)
 	if err != nil {
-		http.Error(w, "Failed to create book: "+err.Error(), http.StatusConflict)
+		apierror.Respond(w, err)
 		return
 	}
-
-	res, _ := json.Marshal(b)
+	res, _ := json.Marshal(book)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
 // DeleteBook removes a book from the database
-func DeleteBook(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	bookId := vars["bookId"]
 	ID, err := strconv.ParseInt(bookId, 10, 64)
 	if err != nil {
-		fmt.Println("error while parsing")
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
+	}
+	var book *models.Book
+	err = h.store.Atomic(func(tx repository.Store) error {
+		book, err = deleteBook(tx, uint(ID), r.Header.Get("If-Match"))
+		return err
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-	book := models.DeleteBook(ID)
 	res, _ := json.Marshal(book)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
-// UpdateBook modifies an existing book's details
-func UpdateBook(w http.ResponseWriter, r *http.Request) {
-	updateBook := &models.Book{}
-	utils.ParseBody(r, updateBook)
+// UpdateBook replaces a book's writable fields with those in the request
+// body; omitted fields are cleared. See saveBook.
+func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bookId := vars["bookId"]
+	ID, err := strconv.ParseInt(bookId, 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
+	}
+
+	replacement := &models.Book{}
+	if err := utils.ParseBody(r, replacement); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	h.saveBook(w, r, uint(ID), replacement)
+}
 
+// PatchBook applies a JSON merge patch to a book: only the fields in the
+// patch change, and null clears a field.
+func (h *Handler) PatchBook(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	bookId := vars["bookId"]
 	ID, err := strconv.ParseInt(bookId, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
+	}
+
+	current, err := h.store.Books().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Book not found"))
+		return
+	}
+	patched := &models.Book{}
+	if err := utils.ParseMergePatch(r, current, patched); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	h.saveBook(w, r, uint(ID), patched)
+}
+
+// saveBook replaces the book with the given ID (see replaceBook) and writes
+// the result with its new ETag.
+func (h *Handler) saveBook(w http.ResponseWriter, r *http.Request, id uint, replacement *models.Book) {
+	err := h.store.Atomic(func(tx repository.Store) error {
+		return replaceBook(tx, id, replacement, r.Header.Get("If-Match"))
+	})
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	bookDetails, db := models.GetBookById(ID)
-	if bookDetails.ID == 0 {
-		http.Error(w, "Book not found", http.StatusNotFound)
+	book, err := h.store.Books().FindByID(id)
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
+	writeResource(w, r, view{}, book)
+}
+
+// createBook validates book and creates it together with one shelf copy per
+// requested copy; availability is then computed from those copies. tx must
+// come from store.Atomic.
+func createBook(tx repository.Store, book *models.Book) error {
+	if err := validate.Struct(book); err != nil {
+		return err
+	}
+	if err := checkCategory(tx, book.CategoryID); err != nil {
+		return err
+	}
+
+	copies := book.Copies
+	if err := tx.Books().Create(book); err != nil {
+		return duplicateISBN(err)
+	}
+	for n := 1; n <= copies; n++ {
+		bookCopy := &models.BookCopy{
+			BookID:  book.ID,
+			Barcode: models.GenerateBarcode(book.ID, n),
+			Status:  models.CopyAvailable,
+		}
+		if err := tx.Copies().Create(bookCopy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Update fields only if they are provided in the request
-	if updateBook.Name != "" {
-		bookDetails.Name = updateBook.Name
+// replaceBook gives the book with the given ID the writable fields of
+// replacement and adds or removes copies until it has replacement.Copies.
+// The computed inventory fields and record metadata are ignored. ifMatch is
+// the client's If-Match header; a stale one fails with 412. tx must come from
+// store.Atomic.
+func replaceBook(tx repository.Store, id uint, replacement *models.Book, ifMatch string) error {
+	if err := validate.Struct(replacement); err != nil {
+		return err
+	}
+	if err := checkCategory(tx, replacement.CategoryID); err != nil {
+		return err
 	}
-	if updateBook.Author != "" {
-		bookDetails.Author = updateBook.Author
+
+	book, err := tx.Books().FindForUpdate(id)
+	if err != nil {
+		return notFound(err, "Book not found")
 	}
-	if updateBook.Publication != "" {
-		bookDetails.Publication = updateBook.Publication
+	if err := checkVersion(ifMatch, book); err != nil {
+		return err
 	}
-	if updateBook.ISBN != "" {
-		bookDetails.ISBN = updateBook.ISBN
+	book.Name = replacement.Name
+	book.Author = replacement.Author
+	book.Publication = replacement.Publication
+	book.ISBN = replacement.ISBN
+	book.Genre = replacement.Genre
+	book.Edition = replacement.Edition
+	book.CategoryID = replacement.CategoryID
+	if err := tx.Books().Update(book); err != nil {
+		return duplicateISBN(err)
 	}
-	if updateBook.Genre != "" {
-		bookDetails.Genre = updateBook.Genre
+	err = setCopyCount(tx, id, replacement.Copies)
+	if errors.Is(err, errCopyInCirculation) {
+		return apierror.Conflict(apierror.CodeCopyInCirculation, "Cannot remove copies that are on loan or on hold")
 	}
-	if updateBook.Edition != "" {
-		bookDetails.Edition = updateBook.Edition
+	return err
+}
+
+// deleteBook moves the book with the given ID to the trash unless ifMatch,
+// the client's If-Match header, is stale. A book with copies on loan or on
+// hold stays, so those loans can still be returned and the holds collected.
+// tx must come from store.Atomic.
+func deleteBook(tx repository.Store, id uint, ifMatch string) (*models.Book, error) {
+	current, err := tx.Books().FindForUpdate(id)
+	if err != nil {
+		return nil, notFound(err, "Book not found")
 	}
-	if updateBook.Copies > 0 { // Check greater than 0 to allow setting copies
-		bookDetails.Copies = updateBook.Copies
+	if err := checkVersion(ifMatch, current); err != nil {
+		return nil, err
 	}
-	if updateBook.Availability != "" {
-		bookDetails.Availability = updateBook.Availability
+	if current.OnLoanCount+current.OnHoldCount > 0 {
+		return nil, apierror.Conflict(apierror.CodeCopyInCirculation, "Cannot delete a book while copies are on loan or on hold")
 	}
-	if updateBook.CategoryID != 0 {
-		bookDetails.CategoryID = updateBook.CategoryID
+	return tx.Books().Delete(id)
+}
+
+// checkCategory reports a 422 validation error unless the category exists.
+func checkCategory(s repository.Store, categoryID uint) error {
+	_, err := s.Categories().FindByID(categoryID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.Validation(apierror.FieldError{Field: "category_id", Code: "not_found", Message: "category does not exist"})
 	}
+	return err
+}
 
-	db.Save(&bookDetails)
-	res, _ := json.Marshal(bookDetails)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+// duplicateISBN explains a duplicate error on a book write: ISBN is the
+// book's only unique field.
+func duplicateISBN(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict(apierror.CodeDuplicate, "A book with this ISBN already exists").
+			WithDetails(apierror.FieldError{Field: "isbn", Code: "duplicate", Message: "isbn is already in use"})
+	}
+	return err
 }
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
+)
+
+// maxBulkOperations bounds the number of operations in one bulk request.
+const maxBulkOperations = 500
+
+// Bulk modes. In atomic mode every operation is applied in one transaction,
+// so the first failure undoes the whole batch; in best-effort mode each
+// operation is applied, or fails, on its own.
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "best_effort"
+)
+
+// BulkRequest is the body of POST /books/bulk.
+type BulkRequest struct {
+	Mode       string          `json:"mode" validate:"oneof=atomic best_effort"`
+	Operations []BulkOperation `json:"operations" validate:"required"`
+}
+
+// BulkOperation is one create, update or delete. Update replaces the book as
+// PUT /books/{bookId} does; IfMatch plays the part of the If-Match header.
+type BulkOperation struct {
+	Op      string       `json:"op" validate:"required,oneof=create update delete"`
+	ID      uint         `json:"id"`
+	IfMatch string       `json:"if_match"`
+	Book    *BookRequest `json:"book"`
+}
+
+// BulkResult reports what happened to the operation at Index. Status is the
+// HTTP status the operation would have got as a request of its own.
+type BulkResult struct {
+	Index  int             `json:"index"`
+	Op     string          `json:"op"`
+	Status int             `json:"status"`
+	ID     uint            `json:"id,omitempty"`
+	Book   *models.Book    `json:"book,omitempty"`
+	Error  *apierror.Error `json:"error,omitempty"`
+}
+
+// BulkResponse counts the operations that succeeded and failed.
+type BulkResponse struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+// BulkBooks applies a batch of book creates, updates and deletes and reports
+// the outcome of each. The response is 200 when every operation succeeded
+// and 207 Multi-Status otherwise.
+func (h *Handler) BulkBooks(w http.ResponseWriter, r *http.Request) {
+	req := &BulkRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if len(req.Operations) == 0 {
+		apierror.Write(w, apierror.Validation(apierror.FieldError{Field: "operations", Code: "required", Message: "operations is required"}))
+		return
+	}
+	if len(req.Operations) > maxBulkOperations {
+		apierror.Write(w, apierror.Validation(apierror.FieldError{
+			Field:   "operations",
+			Code:    "too_many",
+			Message: fmt.Sprintf("operations must not contain more than %d items", maxBulkOperations),
+		}))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = bulkAtomic
+	}
+
+	results := make([]BulkResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = BulkResult{Index: i, Op: op.Op, ID: op.ID}
+	}
+
+	if req.Mode == bulkAtomic {
+		failed := -1
+		err := h.store.Atomic(func(tx repository.Store) error {
+			for i, op := range req.Operations {
+				if err := applyBulkOperation(tx, op, &results[i]); err != nil {
+					failed = i
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil && failed < 0 {
+			apierror.Respond(w, err)
+			return
+		}
+		if err != nil {
+			// Nothing was written: report the failure and mark the rest aborted.
+			aborted := apierror.New(http.StatusFailedDependency, apierror.CodeBulkAborted,
+				fmt.Sprintf("Not applied because operation %d failed", failed))
+			for i := range results {
+				// IDs given to rolled-back creates were never committed.
+				results[i].ID = req.Operations[i].ID
+				if i != failed {
+					setBulkError(&results[i], aborted)
+				} else if e := apierror.From(err); e.Status == http.StatusInternalServerError {
+					apierror.Write(w, e)
+					return
+				} else {
+					setBulkError(&results[i], e)
+				}
+			}
+		}
+	} else {
+		for i, op := range req.Operations {
+			err := h.store.Atomic(func(tx repository.Store) error {
+				return applyBulkOperation(tx, op, &results[i])
+			})
+			if err == nil {
+				continue
+			}
+			e := apierror.From(err)
+			if e.Status == http.StatusInternalServerError {
+				apierror.Write(w, e)
+				return
+			}
+			setBulkError(&results[i], e)
+		}
+	}
+
+	resp := BulkResponse{Mode: req.Mode, Results: results}
+	for i := range results {
+		if results[i].Error != nil {
+			resp.Failed++
+			continue
+		}
+		resp.Succeeded++
+		// Reload once committed so the inventory fields are filled in.
+		if results[i].Op != "delete" {
+			book, err := h.store.Books().FindByID(results[i].ID)
+			if err != nil {
+				apierror.Respond(w, err)
+				return
+			}
+			results[i].Book = book
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	res, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(res)
+}
+
+// applyBulkOperation applies op inside tx, filling in result on success.
+func applyBulkOperation(tx repository.Store, op BulkOperation, result *BulkResult) error {
+	if err := validate.Struct(op); err != nil {
+		return err
+	}
+	switch op.Op {
+	case "create":
+		if op.ID != 0 {
+			return apierror.Validation(apierror.FieldError{Field: "id", Code: "not_allowed", Message: "id must not be given when creating a book"})
+		}
+		if op.Book == nil {
+			return apierror.Validation(apierror.FieldError{Field: "book", Code: "required", Message: "book is required"})
+		}
+		book := op.Book.book()
+		if err := createBook(tx, book); err != nil {
+			return err
+		}
+		result.ID = book.ID
+	case "update":
+		if op.ID == 0 || op.Book == nil {
+			return apierror.Validation(missingBulkFields(op)...)
+		}
+		if err := replaceBook(tx, op.ID, op.Book.book(), op.IfMatch); err != nil {
+			return err
+		}
+	case "delete":
+		if op.ID == 0 {
+			return apierror.Validation(missingBulkFields(op)...)
+		}
+		book, err := deleteBook(tx, op.ID, op.IfMatch)
+		if err != nil {
+			return err
+		}
+		result.Book = book
+	}
+	result.Status = http.StatusOK
+	return nil
+}
+
+// setBulkError records the failure of a bulk operation.
+func setBulkError(result *BulkResult, e *apierror.Error) {
+	result.Status = e.Status
+	result.Error = e
+	result.Book = nil
+}
+
+// missingBulkFields lists the fields op needs but lacks.
+func missingBulkFields(op BulkOperation) []apierror.FieldError {
+	var details []apierror.FieldError
+	if op.ID == 0 {
+		details = append(details, apierror.FieldError{Field: "id", Code: "required", Message: "id is required"})
+	}
+	if op.Op != "delete" && op.Book == nil {
+		details = append(details, apierror.FieldError{Field: "book", Code: "required", Message: "book is required"})
+	}
+	return details
+}
@@ -2,122 +2,205 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
 	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 	"github.com/gorilla/mux"
 )
 
-// CreateCategory handles the creation of a new book category.
-func CreateCategory(w http.ResponseWriter, r *http.Request) {
-	newCategory := &models.Category{}
-	utils.ParseBody(r, newCategory)
+// CategoryRequest is the body of POST /categories.
+type CategoryRequest struct {
+	Name string `json:"name" validate:"required"`
+}
 
-	c, err := newCategory.CreateCategory()
-	if err != nil {
-		http.Error(w, "Failed to create category: "+err.Error(), http.StatusConflict)
+// CreateCategory handles the creation of a new book category.
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	req := &CategoryRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	newCategory := &models.Category{Name: req.Name}
+	if err := h.store.Categories().Create(newCategory); err != nil {
+		apierror.Respond(w, duplicateCategory(err))
 		return
 	}
 
-	res, _ := json.Marshal(c)
+	res, _ := json.Marshal(newCategory)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
-// GetAllCategories retrieves all categories.
-func GetAllCategories(w http.ResponseWriter, r *http.Request) {
-	db := models.GetDB()
-	var categories []models.Category
-	db.Find(&categories)
-
-	res, _ := json.Marshal(categories)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+// GetAllCategories retrieves a page of categories, optionally filtered and sorted.
+func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, models.Category{})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	q, p, err := parseListQuery(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	categories, total, err := h.store.Categories().List(q)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	writeList(w, r, v, categories, total, p)
 }
 
 // GetCategoryById retrieves a single category by its ID.
-func GetCategoryById(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetCategoryById(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	categoryIdStr := vars["categoryId"]
 	ID, err := strconv.ParseInt(categoryIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid category ID"))
 		return
 	}
-
-	db := models.GetDB()
-	var category models.Category
-	if result := db.First(&category, ID); result.Error != nil {
-		http.Error(w, "Category not found", http.StatusNotFound)
+	v, err := parseView(r, models.Category{})
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	res, _ := json.Marshal(category)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+	category, err := h.store.Categories().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Category not found"))
+		return
+	}
+	writeResource(w, r, v, category)
 }
 
-// UpdateCategory updates a category's name.
-func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+// UpdateCategory replaces a category's name.
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	categoryIdStr := vars["categoryId"]
 	ID, err := strconv.ParseInt(categoryIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid category ID"))
 		return
 	}
 
-	updateData := &models.Category{}
-	utils.ParseBody(r, updateData)
-
-	db := models.GetDB()
-	var category models.Category
-	if result := db.First(&category, ID); result.Error != nil {
-		http.Error(w, "Category not found", http.StatusNotFound)
+	replacement := &models.Category{}
+	if err := utils.ParseBody(r, replacement); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
-
-	category.Name = updateData.Name
-	db.Save(&category)
-
-	res, _ := json.Marshal(category)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+	h.saveCategory(w, r, uint(ID), replacement)
 }
 
-// DeleteCategory removes a category.
-func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+// PatchCategory applies a JSON merge patch to a category.
+func (h *Handler) PatchCategory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	categoryIdStr := vars["categoryId"]
 	ID, err := strconv.ParseInt(categoryIdStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid category ID"))
 		return
 	}
 
-	db := models.GetDB()
+	current, err := h.store.Categories().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Category not found"))
+		return
+	}
+	patched := &models.Category{}
+	if err := utils.ParseMergePatch(r, current, patched); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	h.saveCategory(w, r, uint(ID), patched)
+}
 
-	// Safety check: Prevent deleting a category if it still has books.
-	var bookCount int64
-	db.Model(&models.Book{}).Where("category_id = ?", ID).Count(&bookCount)
-	if bookCount > 0 {
-		http.Error(w, "Cannot delete category: it is still associated with books", http.StatusConflict)
+// saveCategory gives the category with the given ID the writable fields of
+// replacement. A stale If-Match fails with 412.
+func (h *Handler) saveCategory(w http.ResponseWriter, r *http.Request, id uint, replacement *models.Category) {
+	if err := validate.Struct(replacement); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	var category models.Category
-	if result := db.First(&category, ID); result.Error != nil {
-		http.Error(w, "Category not found", http.StatusNotFound)
+	err := h.store.Atomic(func(tx repository.Store) error {
+		category, err := tx.Categories().FindForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, category); err != nil {
+			return err
+		}
+		category.Name = replacement.Name
+		return tx.Categories().Update(category)
+	})
+	if err != nil {
+		apierror.Respond(w, duplicateCategory(notFound(err, "Category not found")))
 		return
 	}
 
-	db.Delete(&category)
+	// Reload so the ETag matches what a later GET returns.
+	category, err := h.store.Categories().FindByID(id)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	writeResource(w, r, view{}, category)
+}
+
+// DeleteCategory removes a category.
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryIdStr := vars["categoryId"]
+	ID, err := strconv.ParseInt(categoryIdStr, 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid category ID"))
+		return
+	}
+
+	err = h.store.Atomic(func(tx repository.Store) error {
+		current, err := tx.Categories().FindForUpdate(uint(ID))
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, current); err != nil {
+			return err
+		}
+
+		// Safety check: Prevent deleting a category if it still has books.
+		bookCount, err := tx.Books().CountByCategory(uint(ID))
+		if err != nil {
+			return err
+		}
+		if bookCount > 0 {
+			return apierror.Conflict(apierror.CodeCategoryInUse, "Cannot delete category: it is still associated with books")
+		}
+		return tx.Categories().Delete(uint(ID))
+	})
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Category not found"))
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent) // 204 No Content is a good response for a successful delete
 }
+
+// duplicateCategory explains a duplicate error on a category write.
+func duplicateCategory(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict(apierror.CodeDuplicate, "A category with this name already exists").
+			WithDetails(apierror.FieldError{Field: "name", Code: "duplicate", Message: "name is already in use"})
+	}
+	return err
+}
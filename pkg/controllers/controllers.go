@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"github.com/J-Mihir/go-bookstore/pkg/circulation"
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/mail"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/search"
+)
+
+// Handler serves the API endpoints. Each App builds its own, so several can
+// run side by side in one process, e.g. in parallel tests.
+type Handler struct {
+	// store is the data access layer shared by every handler.
+	store repository.Store
+	// searcher answers catalog searches.
+	searcher search.Backend
+	// mailer sends password reset emails.
+	mailer mail.Sender
+	// circulation performs borrow and return operations atomically.
+	circulation *circulation.Service
+	// auth holds the secret and lifetime used to issue tokens.
+	auth config.AuthConfig
+	// trash holds how long deleted records stay restorable.
+	trash config.TrashConfig
+}
+
+// New returns a Handler serving from s, searching with b and sending email
+// through m.
+func New(s repository.Store, b search.Backend, m mail.Sender, cfg *config.Config) *Handler {
+	return &Handler{
+		store:       s,
+		searcher:    b,
+		mailer:      m,
+		circulation: circulation.NewService(s),
+		auth:        cfg.Auth,
+		trash:       cfg.Trash,
+	}
+}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxBarcodeAttempts bounds the search for a free generated barcode.
+const maxBarcodeAttempts = 100
+
+var errCopyInCirculation = errors.New("copy is on loan or on hold")
+
+// errCopyLostOnLoan is a request to mark a borrowed copy Lost, which has to
+// close the loan too.
+var errCopyLostOnLoan = errors.New("copy is on loan")
+
+// staffCopyStatuses are the statuses staff may set by hand; Borrowed and
+// Reserved are only reached through circulation.
+var staffCopyStatuses = map[models.CopyStatus]bool{
+	models.CopyAvailable: true,
+	models.CopyLost:      true,
+	models.CopyWithdrawn: true,
+}
+
+// GetBookCopies lists the physical copies of a book.
+func (h *Handler) GetBookCopies(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseUint(mux.Vars(r)["bookId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
+	}
+	v, err := parseView(r, models.BookCopy{})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if _, err := h.store.Books().FindByID(uint(bookID)); err != nil {
+		apierror.Respond(w, notFound(err, "Book not found"))
+		return
+	}
+
+	copies, err := h.store.Copies().ListByBook(uint(bookID))
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	v.write(w, http.StatusOK, copies)
+}
+
+// BookCopyRequest is the body of POST /books/{bookId}/copies. A new copy always
+// starts on the shelf.
+type BookCopyRequest struct {
+	Barcode   string `json:"barcode"`
+	Location  string `json:"location"`
+	Condition string `json:"condition"`
+}
+
+// CreateBookCopy adds a new copy of a book to the shelf. A barcode is
+// generated when none is given.
+func (h *Handler) CreateBookCopy(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseUint(mux.Vars(r)["bookId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return
+	}
+	if _, err := h.store.Books().FindByID(uint(bookID)); err != nil {
+		apierror.Respond(w, notFound(err, "Book not found"))
+		return
+	}
+
+	req := &BookCopyRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	newCopy := &models.BookCopy{
+		BookID:    uint(bookID),
+		Barcode:   req.Barcode,
+		Status:    models.CopyAvailable,
+		Location:  req.Location,
+		Condition: req.Condition,
+	}
+
+	if newCopy.Barcode != "" {
+		err = h.store.Copies().Create(newCopy)
+	} else {
+		err = createWithGeneratedBarcode(h.store, newCopy)
+	}
+	if err != nil {
+		apierror.Respond(w, duplicateBarcode(err))
+		return
+	}
+
+	res, _ := json.Marshal(newCopy)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
+}
+
+// createWithGeneratedBarcode numbers the copy after all of the book's copies,
+// including deleted ones whose barcodes stay reserved, and skips barcodes that
+// are still taken (e.g. set by hand). Starting past the deleted copies keeps
+// failed inserts, which abort a PostgreSQL transaction, to that rare case.
+func createWithGeneratedBarcode(s repository.Store, bookCopy *models.BookCopy) error {
+	count, err := s.Copies().CountAllByBook(bookCopy.BookID)
+	if err != nil {
+		return err
+	}
+	start := int(count) + 1
+	for n := start; n < start+maxBarcodeAttempts; n++ {
+		bookCopy.Barcode = models.GenerateBarcode(bookCopy.BookID, n)
+		err = s.Copies().Create(bookCopy)
+		if !errors.Is(err, repository.ErrDuplicate) {
+			return err
+		}
+	}
+	return err
+}
+
+// copyRemovalOrder is the order in which setCopyCount removes copies.
+var copyRemovalOrder = map[models.CopyStatus]int{
+	models.CopyWithdrawn: 0,
+	models.CopyLost:      1,
+	models.CopyAvailable: 2,
+}
+
+// setCopyCount adds shelf copies to, or removes copies from, a book until it
+// has want copies. Withdrawn and lost copies are removed first, then copies
+// on the shelf, newest first; copies on loan or on hold are never removed.
+// It must run inside store.Atomic with the book locked.
+func setCopyCount(tx repository.Store, bookID uint, want int) error {
+	copies, err := tx.Copies().ListByBook(bookID)
+	if err != nil {
+		return err
+	}
+	for n := len(copies); n < want; n++ {
+		if err := createWithGeneratedBarcode(tx, &models.BookCopy{BookID: bookID, Status: models.CopyAvailable}); err != nil {
+			return duplicateBarcode(err)
+		}
+	}
+
+	surplus := len(copies) - want
+	if surplus <= 0 {
+		return nil
+	}
+	var removable []models.BookCopy
+	for _, c := range copies {
+		if _, ok := copyRemovalOrder[c.Status]; ok {
+			removable = append(removable, c)
+		}
+	}
+	if len(removable) < surplus {
+		return errCopyInCirculation
+	}
+	slices.SortFunc(removable, func(a, b models.BookCopy) int {
+		if c := cmp.Compare(copyRemovalOrder[a.Status], copyRemovalOrder[b.Status]); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	for _, c := range removable[:surplus] {
+		if err := tx.Copies().Delete(c.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateBookCopy changes a copy's barcode, location, condition or status.
+// Staff may mark a copy on the shelf as Lost or Withdrawn and back again,
+// subject to the copy status transitions; copies on loan or on hold cannot
+// be changed by hand. A borrowed copy is marked Lost through its loan, with
+// PUT /transactions/{transactionId}/lost.
+func (h *Handler) UpdateBookCopy(w http.ResponseWriter, r *http.Request) {
+	bookID, copyID, ok := copyPathIDs(w, r)
+	if !ok {
+		return
+	}
+
+	updateData := &models.BookCopy{}
+	if err := utils.ParseBody(r, updateData); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	if updateData.Status != "" {
+		status, err := models.ParseCopyStatus(string(updateData.Status))
+		if err != nil || !staffCopyStatuses[status] {
+			apierror.Write(w, apierror.Validation(apierror.FieldError{
+				Field: "status", Code: "invalid", Message: "status must be Available, Lost or Withdrawn",
+			}))
+			return
+		}
+	}
+
+	var bookCopy *models.BookCopy
+	err := h.store.Atomic(func(tx repository.Store) error {
+		// Lock the book so the change cannot race a checkout of this copy.
+		if _, err := tx.Books().FindForUpdate(bookID); err != nil {
+			return err
+		}
+		var err error
+		if bookCopy, err = tx.Copies().FindForUpdate(copyID); err != nil {
+			return err
+		}
+		if bookCopy.BookID != bookID {
+			return repository.ErrNotFound
+		}
+
+		if updateData.Status != "" && updateData.Status != bookCopy.Status {
+			if bookCopy.Status == models.CopyBorrowed && updateData.Status == models.CopyLost {
+				return errCopyLostOnLoan
+			}
+			if bookCopy.Status == models.CopyBorrowed || bookCopy.Status == models.CopyReserved {
+				return errCopyInCirculation
+			}
+			if err := bookCopy.TransitionTo(updateData.Status); err != nil {
+				return err
+			}
+		}
+		if updateData.Barcode != "" {
+			bookCopy.Barcode = updateData.Barcode
+		}
+		if updateData.Location != "" {
+			bookCopy.Location = updateData.Location
+		}
+		if updateData.Condition != "" {
+			bookCopy.Condition = updateData.Condition
+		}
+		return tx.Copies().Update(bookCopy)
+	})
+	var transitionErr *models.InvalidTransitionError
+	switch {
+	case errors.Is(err, errCopyInCirculation):
+		apierror.Write(w, apierror.Conflict(apierror.CodeCopyInCirculation, "Cannot change the status of a copy that is on loan or on hold"))
+		return
+	case errors.Is(err, errCopyLostOnLoan):
+		apierror.Write(w, apierror.Conflict(apierror.CodeCopyInCirculation, "The copy is on loan: mark the loan lost with PUT /transactions/{transactionId}/lost"))
+		return
+	case errors.As(err, &transitionErr):
+		apierror.Write(w, apierror.Conflict(apierror.CodeInvalidTransition, "Invalid status change: "+transitionErr.Error()))
+		return
+	case err != nil:
+		apierror.Respond(w, duplicateBarcode(notFound(err, "Copy not found")))
+		return
+	}
+
+	res, _ := json.Marshal(bookCopy)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// DeleteBookCopy removes a copy that is not currently on loan or on hold.
+func (h *Handler) DeleteBookCopy(w http.ResponseWriter, r *http.Request) {
+	bookID, copyID, ok := copyPathIDs(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.store.Atomic(func(tx repository.Store) error {
+		if _, err := tx.Books().FindForUpdate(bookID); err != nil {
+			return err
+		}
+		bookCopy, err := tx.Copies().FindForUpdate(copyID)
+		if err != nil {
+			return err
+		}
+		if bookCopy.BookID != bookID {
+			return repository.ErrNotFound
+		}
+		if bookCopy.Status == models.CopyBorrowed || bookCopy.Status == models.CopyReserved {
+			return errCopyInCirculation
+		}
+		return tx.Copies().Delete(copyID)
+	})
+	switch {
+	case errors.Is(err, errCopyInCirculation):
+		apierror.Write(w, apierror.Conflict(apierror.CodeCopyInCirculation, "Cannot delete a copy that is on loan or on hold"))
+		return
+	case err != nil:
+		apierror.Respond(w, notFound(err, "Copy not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// copyPathIDs parses the bookId and copyId route variables, writing a 400 response on failure.
+func copyPathIDs(w http.ResponseWriter, r *http.Request) (bookID, copyID uint, ok bool) {
+	vars := mux.Vars(r)
+	b, err := strconv.ParseUint(vars["bookId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid book ID"))
+		return 0, 0, false
+	}
+	c, err := strconv.ParseUint(vars["copyId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid copy ID"))
+		return 0, 0, false
+	}
+	return uint(b), uint(c), true
+}
+
+// duplicateBarcode explains a duplicate error on a copy write: the barcode is
+// the copy's only unique field.
+func duplicateBarcode(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict(apierror.CodeDuplicate, "A copy with this barcode already exists").
+			WithDetails(apierror.FieldError{Field: "barcode", Code: "duplicate", Message: "barcode is already in use"})
+	}
+	return err
+}
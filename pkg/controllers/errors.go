@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// notFound replaces repository.ErrNotFound with a 404 naming the missing record.
+func notFound(err error, message string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound(message)
+	}
+	return err
+}
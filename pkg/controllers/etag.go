@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+)
+
+// errStale is returned when If-Match names a version that is no longer current.
+var errStale = apierror.New(http.StatusPreconditionFailed, apierror.CodePreconditionFailed,
+	"The record has changed since it was read; fetch it again and retry")
+
+// etag is the entity tag of a resource: a hash of its JSON representation, so
+// any change to the response body, including computed fields, changes it.
+func etag(v any) string {
+	b, _ := json.Marshal(v)
+	return bodyETag(b)
+}
+
+// bodyETag is the entity tag of a response body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// checkIfMatch returns errStale unless the request's If-Match header, if any,
+// names the current version of the resource. Call it with current read inside
+// the same store.Atomic as the write, so no other write can slip in between.
+func checkIfMatch(r *http.Request, current any) error {
+	return checkVersion(r.Header.Get("If-Match"), current)
+}
+
+// checkVersion is checkIfMatch for an If-Match value given on its own.
+func checkVersion(ifMatch string, current any) error {
+	if ifMatch == "" || matchesETag(ifMatch, etag(current), false) {
+		return nil
+	}
+	return errStale
+}
+
+// matchesETag reports whether a comma-separated If-Match or If-None-Match
+// header value matches tag. "*" matches any version. Weak comparison, used for
+// If-None-Match, ignores the W/ prefix.
+func matchesETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeResource writes a single book, user or category, shaped by v, with its
+// ETag. The tag covers the body actually sent, so only the full record's tag
+// is accepted by If-Match. A GET whose If-None-Match already names that
+// version gets 304 Not Modified.
+func writeResource(w http.ResponseWriter, r *http.Request, v view, resource any) {
+	res := v.render(resource)
+	tag := bodyETag(res)
+	w.Header().Set("ETag", tag)
+	if r.Method == http.MethodGet && matchesETag(r.Header.Get("If-None-Match"), tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// listParams are the query parameters every list endpoint understands.
+// Any other parameter filters the results on the field of the same name.
+var listParams = map[string]bool{"page": true, "per_page": true, "sort": true, "include": true, "fields": true}
+
+// page is the position of one page within a list response.
+type page struct {
+	number  int
+	perPage int
+}
+
+// parseListQuery reads pagination, sorting and filters from the request's
+// query string, e.g. ?page=2&per_page=20&sort=-created_at,name&author=tolkien.
+func parseListQuery(r *http.Request) (repository.ListQuery, page, error) {
+	p, err := parsePage(r)
+	if err != nil {
+		return repository.ListQuery{}, p, err
+	}
+
+	params := r.URL.Query()
+	q := repository.ListQuery{
+		Filters: map[string]string{},
+		Sort:    repository.ParseSort(params.Get("sort")),
+		Offset:  p.offset(),
+		Limit:   p.perPage,
+	}
+	for name, values := range params {
+		if !listParams[name] {
+			q.Filters[name] = values[0]
+		}
+	}
+	return q, p, nil
+}
+
+// parsePage reads the page and per_page query parameters.
+func parsePage(r *http.Request) (page, error) {
+	params := r.URL.Query()
+	p := page{number: 1, perPage: defaultPerPage}
+
+	if v := params.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, "page must be a positive integer")
+		}
+		p.number = n
+	}
+	if v := params.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			return p, apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, fmt.Sprintf("per_page must be between 1 and %d", maxPerPage))
+		}
+		p.perPage = n
+	}
+	return p, nil
+}
+
+// offset is the number of records before the page.
+func (p page) offset() int {
+	return (p.number - 1) * p.perPage
+}
+
+// writeList writes one page of a list response, shaped by v. The body is the
+// array of items; X-Total-Count carries the number of matching records and
+// Link the first, prev, next and last pages.
+func writeList[T any](w http.ResponseWriter, r *http.Request, v view, items []T, total int64, p page) {
+	lastPage := max(1, int((total+int64(p.perPage)-1)/int64(p.perPage)))
+	links := []string{pageLink(r, 1, p.perPage, "first")}
+	if p.number > 1 {
+		links = append(links, pageLink(r, min(p.number-1, lastPage), p.perPage, "prev"))
+	}
+	if p.number < lastPage {
+		links = append(links, pageLink(r, p.number+1, p.perPage, "next"))
+	}
+	links = append(links, pageLink(r, lastPage, p.perPage, "last"))
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	// Add, not Set: deprecated paths already carry a successor-version link.
+	w.Header().Add("Link", strings.Join(links, ", "))
+	v.write(w, http.StatusOK, items)
+}
+
+// pageLink returns a Link header entry for the given page of the current request.
+func pageLink(r *http.Request, number, perPage int, rel string) string {
+	params := r.URL.Query()
+	params.Set("page", strconv.Itoa(number))
+	params.Set("per_page", strconv.Itoa(perPage))
+	u := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/mail"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
+)
+
+// ForgotPasswordRequest is the body of POST /password/forgot.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest is the body of POST /password/reset.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// ChangePasswordRequest is the body of PUT /me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// maxResetEmails is how many reset emails one account can be sent within
+// auth.password_reset_ttl. Further requests are accepted but send nothing,
+// so the endpoint cannot be used to flood someone's inbox.
+const maxResetEmails = 3
+
+// errResetLimit stops ForgotPassword from issuing another token.
+var errResetLimit = errors.New("password reset email limit reached")
+
+// errInvalidResetToken covers unknown, expired and used reset tokens.
+var errInvalidResetToken = apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired password reset token")
+
+// ForgotPassword emails a single-use token for choosing a new password. Only
+// the newest token works. The response is 202 whether or not the email
+// belongs to an account or the account reached maxResetEmails, and the email
+// is sent in the background, so neither the status nor the timing can be used
+// to discover accounts.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	user, err := h.store.Users().FindByEmail(req.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	requestID := w.Header().Get(apierror.HeaderRequestID)
+	now := time.Now()
+	token := randomToken(32)
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(h.auth.PasswordResetTTL),
+	}
+	err = h.store.Atomic(func(tx repository.Store) error {
+		// Lock the user so concurrent requests count each other's tokens.
+		if _, err := tx.Users().FindForUpdate(user.ID); err != nil {
+			return err
+		}
+		issued, err := tx.PasswordResets().CountIssuedSince(user.ID, now.Add(-h.auth.PasswordResetTTL))
+		if err != nil {
+			return err
+		}
+		if issued >= maxResetEmails {
+			return errResetLimit
+		}
+		if err := tx.PasswordResets().UseAll(user.ID, now); err != nil {
+			return err
+		}
+		return tx.PasswordResets().Create(reset)
+	})
+	if errors.Is(err, errResetLimit) {
+		log.Printf("request %s: not sending another password reset email to user %d: %v", requestID, user.ID, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	message := h.resetEmail(user, token, reset.ExpiresAt)
+	go func() {
+		// Failing the request would tell the caller the account exists.
+		if err := h.mailer.Send(message); err != nil {
+			log.Printf("request %s: sending password reset email to user %d: %v", requestID, user.ID, err)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token is used up, and every session of the user is revoked.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	err := h.store.Atomic(func(tx repository.Store) error {
+		reset, err := tx.PasswordResets().FindByHashForUpdate(hashToken(req.Token))
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+			return errInvalidResetToken
+		}
+
+		user, err := tx.Users().FindForUpdate(reset.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		// The password hashing is handled by the BeforeSave hook in the User model.
+		user.Password = req.Password
+		if err := tx.Users().Update(user); err != nil {
+			return err
+		}
+		if err := tx.PasswordResets().UseAll(user.ID, now); err != nil {
+			return err
+		}
+		return tx.RefreshTokens().RevokeUser(user.ID, now)
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword sets the caller's password after checking the current one.
+// Every other session of the caller is revoked; the one making the request
+// stays signed in.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, err := caller(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	var req ChangePasswordRequest
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	err = h.store.Atomic(func(tx repository.Store) error {
+		user, err := tx.Users().FindForUpdate(claims.UserID)
+		if err != nil {
+			return notFound(err, "User not found")
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+			return apierror.Validation(apierror.FieldError{
+				Field: "current_password", Code: "incorrect", Message: "current_password is incorrect",
+			})
+		}
+		user.Password = req.NewPassword
+		if err := tx.Users().Update(user); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.PasswordResets().UseAll(user.ID, now); err != nil {
+			return err
+		}
+		return tx.RefreshTokens().RevokeOtherSessions(user.ID, claims.ID, now)
+	})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetEmail is the message carrying a password reset token. With
+// auth.password_reset_url it links to that page, otherwise it gives the token.
+func (h *Handler) resetEmail(user *models.User, token string, expires time.Time) mail.Message {
+	instructions := fmt.Sprintf("Send this token with a new password to POST /api/v1/password/reset:\n\n    %s", token)
+	if h.auth.PasswordResetURL != "" {
+		link, _ := url.Parse(h.auth.PasswordResetURL) // checked by config.Validate
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		instructions = fmt.Sprintf("Choose a new password here:\n\n    %s", link)
+	}
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your BookHive password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your BookHive account. %s\n\n"+
+			"This can be done once, until %s. If you did not ask for a reset, ignore this email: your password has not changed.\n",
+			user.Name, instructions, expires.UTC().Format(time.RFC1123)),
+	}
+}
@@ -1,68 +1,84 @@
 package controllers
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 )
 
+// ReservationRequest is the body of POST /reservations.
+type ReservationRequest struct {
+	UserID uint `json:"user_id"` // optional: reserve for someone else with reservations:manage
+	BookID uint `json:"book_id" validate:"required"`
+}
+
 // CreateReservation handles a user's request to reserve a book.
-func CreateReservation(w http.ResponseWriter, r *http.Request) {
-	type ReservationRequest struct {
-		UserID uint `json:"user_id"`
-		BookID uint `json:"book_id"`
+func (h *Handler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, models.Reservation{}, "user", "book")
+	if err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-
 	var req ReservationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
-
-	db := models.GetDB()
 
 	// 1. Validate the user
-	user, _ := models.GetUserById(int64(req.UserID))
-	if user.ID == 0 {
-		http.Error(w, "User not found", http.StatusNotFound)
+	userID, err := h.actingUser(r, req.UserID, models.PermReservationsManage)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if _, err := h.store.Users().FindByID(userID); err != nil {
+		apierror.Respond(w, notFound(err, "User not found"))
 		return
 	}
 
 	// 2. Validate the book
-	book, _ := models.GetBookById(int64(req.BookID))
-	if book.ID == 0 {
-		http.Error(w, "Book not found", http.StatusNotFound)
+	book, err := h.store.Books().FindByID(req.BookID)
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Book not found"))
 		return
 	}
 
-	// 3. Business Rule: A user can only reserve a book if it's currently "Borrowed".
-	if book.Availability != "Borrowed" {
-		http.Error(w, "This book is not currently borrowed and cannot be reserved.", http.StatusConflict)
+	// 3. Business Rule: A user can only reserve a book when no copy is on the
+	// shelf and at least one is out on loan or on hold.
+	if book.AvailableCount > 0 || book.OnLoanCount+book.OnHoldCount == 0 {
+		apierror.Write(w, apierror.Conflict(apierror.CodeNotReservable, "This book is not currently borrowed and cannot be reserved"))
 		return
 	}
 
 	// 4. Check if the user already has a pending reservation for this book
-	var existingReservation models.Reservation
-	db.Where("user_id = ? AND book_id = ? AND status = ?", req.UserID, req.BookID, "Pending").First(&existingReservation)
-	if existingReservation.ID != 0 {
-		http.Error(w, "You already have a pending reservation for this book.", http.StatusConflict)
+	if _, err := h.store.Reservations().FindPending(userID, req.BookID); err == nil {
+		apierror.Write(w, apierror.Conflict(apierror.CodeAlreadyReserved, "You already have a pending reservation for this book"))
 		return
 	}
 
 	// 5. Create the reservation
 	reservation := models.Reservation{
-		UserID: req.UserID,
+		UserID: userID,
 		BookID: req.BookID,
 		Status: "Pending",
 	}
 
-	if result := db.Create(&reservation); result.Error != nil {
-		http.Error(w, "Failed to create reservation", http.StatusInternalServerError)
+	if err := h.store.Reservations().Create(&reservation); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(reservation)
+	reservation.User, reservation.Book, err = h.includeUserAndBook(v, reservation.UserID, reservation.BookID)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	v.write(w, http.StatusOK, reservation)
 }
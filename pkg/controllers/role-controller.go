@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
+	"github.com/gorilla/mux"
+)
+
+// validRoleName keeps role names usable as identifiers in tokens and filters.
+var validRoleName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// errAdminRole protects the role that grants everything.
+var errAdminRole = apierror.Conflict(apierror.CodeRoleProtected, "The admin role cannot be changed or deleted")
+
+// GetPermissions lists every permission a role can grant.
+func (h *Handler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	res, _ := json.Marshal(models.Permissions)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// GetRoles lists every role with its permissions.
+func (h *Handler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.store.Roles().List()
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	res, _ := json.Marshal(roles)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// GetRoleById retrieves a single role by its ID.
+func (h *Handler) GetRoleById(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseInt(mux.Vars(r)["roleId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid role ID"))
+		return
+	}
+	role, err := h.store.Roles().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Role not found"))
+		return
+	}
+	writeResource(w, r, view{}, role)
+}
+
+// RoleRequest is the body of POST /roles.
+type RoleRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateRole adds a role granting the given permissions.
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	req := &RoleRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	role := &models.Role{Name: req.Name, Description: req.Description, Permissions: req.Permissions}
+	if err := checkRole(role); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := h.store.Atomic(func(tx repository.Store) error { return tx.Roles().Create(role) }); err != nil {
+		apierror.Respond(w, duplicateRole(err))
+		return
+	}
+
+	res, _ := json.Marshal(role)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
+}
+
+// UpdateRole replaces a role's description and permissions. Its name cannot
+// change, since users refer to their role by name. A stale If-Match fails
+// with 412.
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseInt(mux.Vars(r)["roleId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid role ID"))
+		return
+	}
+	replacement := &models.Role{}
+	if err := utils.ParseBody(r, replacement); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := checkRole(replacement); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	err = h.store.Atomic(func(tx repository.Store) error {
+		role, err := tx.Roles().FindForUpdate(uint(ID))
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, role); err != nil {
+			return err
+		}
+		if role.Name == models.AdminRole {
+			return errAdminRole
+		}
+		if replacement.Name != role.Name {
+			return apierror.Validation(apierror.FieldError{Field: "name", Code: "read_only", Message: "name cannot be changed"})
+		}
+		role.Description = replacement.Description
+		role.Permissions = replacement.Permissions
+		return tx.Roles().Update(role)
+	})
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Role not found"))
+		return
+	}
+
+	// Reload so the ETag matches what a later GET returns.
+	role, err := h.store.Roles().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	writeResource(w, r, view{}, role)
+}
+
+// DeleteRole permanently removes a role no user holds.
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	ID, err := strconv.ParseInt(mux.Vars(r)["roleId"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid role ID"))
+		return
+	}
+
+	err = h.store.Atomic(func(tx repository.Store) error {
+		role, err := tx.Roles().FindForUpdate(uint(ID))
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, role); err != nil {
+			return err
+		}
+		if role.Name == models.AdminRole {
+			return errAdminRole
+		}
+		_, holders, err := tx.Users().List(repository.ListQuery{Filters: map[string]string{"role": role.Name}, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if holders > 0 {
+			return apierror.Conflict(apierror.CodeRoleInUse, fmt.Sprintf("Cannot delete role: it is held by %d users", holders))
+		}
+		return tx.Roles().Delete(role.ID)
+	})
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Role not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkRole validates a role from a request body and puts its permissions
+// in canonical order.
+func checkRole(role *models.Role) error {
+	details := validate.Fields(role)
+	if role.Name != "" && !validRoleName.MatchString(role.Name) {
+		details = append(details, apierror.FieldError{
+			Field: "name", Code: "invalid",
+			Message: "name must be lowercase letters, digits and underscores, starting with a letter",
+		})
+	}
+	known := make([]string, len(models.Permissions))
+	for i, p := range models.Permissions {
+		known[i] = p.Name
+	}
+	for _, p := range role.Permissions {
+		if !slices.Contains(known, p) {
+			details = append(details, apierror.FieldError{
+				Field: "permissions", Code: "unknown", Message: fmt.Sprintf("%q is not a permission", p),
+			})
+		}
+	}
+	if len(details) > 0 {
+		return apierror.Validation(details...)
+	}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	slices.Sort(role.Permissions)
+	role.Permissions = slices.Compact(role.Permissions)
+	return nil
+}
+
+// duplicateRole explains a duplicate error on a role write.
+func duplicateRole(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict(apierror.CodeDuplicate, "A role with this name already exists").
+			WithDetails(apierror.FieldError{Field: "name", Code: "duplicate", Message: "name is already in use"})
+	}
+	return err
+}
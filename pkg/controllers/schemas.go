@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// Schemas maps each component schema of the OpenAPI document to the Go type
+// the API reads or writes for it. openapi.Check compares the two, so a change
+// to one of these types must be made to the document as well.
+var Schemas = map[string]any{
+	"Record":                gorm.Model{},
+	"Book":                  models.Book{},
+	"BookCopy":              models.BookCopy{},
+	"SearchResult":          SearchResult{},
+	"User":                  models.User{},
+	"Category":              models.Category{},
+	"Role":                  models.Role{},
+	"Permission":            models.Permission{},
+	"Transaction":           models.Transaction{},
+	"Reservation":           models.Reservation{},
+	"UserRequest":           UserRequest{},
+	"BookRequest":           BookRequest{},
+	"BookCopyRequest":       BookCopyRequest{},
+	"CategoryRequest":       CategoryRequest{},
+	"RoleRequest":           RoleRequest{},
+	"BorrowRequest":         BorrowRequest{},
+	"ReservationRequest":    ReservationRequest{},
+	"LoginRequest":          LoginRequest{},
+	"TokenResponse":         TokenResponse{},
+	"RefreshRequest":        RefreshRequest{},
+	"ForgotPasswordRequest": ForgotPasswordRequest{},
+	"ResetPasswordRequest":  ResetPasswordRequest{},
+	"ChangePasswordRequest": ChangePasswordRequest{},
+	"PurgeResult":           repository.PurgeResult{},
+	"PurgeResponse":         PurgeResponse{},
+	"BulkRequest":           BulkRequest{},
+	"BulkOperation":         BulkOperation{},
+	"BulkResult":            BulkResult{},
+	"BulkResponse":          BulkResponse{},
+	"FieldError":            apierror.FieldError{},
+	"Error":                 apierror.Error{},
+	"ErrorResponse": struct {
+		Error *apierror.Error `json:"error"`
+	}{},
+}
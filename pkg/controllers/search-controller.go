@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/search"
+)
+
+// SearchResult is a book in the search results together with its relevance.
+type SearchResult struct {
+	models.Book
+	Score float64 `json:"score"`
+}
+
+// SearchBooks searches the catalog, e.g. GET /books/search?q=hobbit author:tolkien.
+// Results are ordered by relevance and paginated like the list endpoints.
+func (h *Handler) SearchBooks(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, SearchResult{}, "category")
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	q, err := search.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		apierror.Write(w, apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, "Query parameter q is required"))
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	hits, total, err := h.searcher.Search(q, p.offset(), p.perPage)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	scores := make(map[uint]float64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.BookID
+		scores[hit.BookID] = hit.Score
+	}
+	books, err := h.store.Books().FindByIDs(ids)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	results := make([]SearchResult, len(books))
+	for i, book := range books {
+		results[i] = SearchResult{Book: book, Score: scores[book.ID]}
+	}
+	if v.include["category"] {
+		refs := make([]*models.Book, len(results))
+		for i := range results {
+			refs[i] = &results[i].Book
+		}
+		if err := h.includeCategories(refs...); err != nil {
+			apierror.Respond(w, err)
+			return
+		}
+	}
+	writeList(w, r, v, results, total, p)
+}
@@ -1,130 +1,153 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/circulation"
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 	"github.com/gorilla/mux"
 )
 
-const maxBorrowLimit = 5 // Business Rule: A user can borrow a maximum of 5 books
+// BorrowRequest is the body of POST /transactions/borrow. UserID defaults
+// to the caller; borrowing for someone else needs loans:manage.
+type BorrowRequest struct {
+	UserID  uint   `json:"user_id"`
+	BookID  uint   `json:"book_id" validate:"required"`
+	Barcode string `json:"barcode"` // optional: lend this specific copy
+}
 
 // BorrowBook handles the logic for a user borrowing a book.
-func BorrowBook(w http.ResponseWriter, r *http.Request) {
-	// This function remains unchanged
-	type BorrowRequest struct {
-		UserID uint `json:"user_id"`
-		BookID uint `json:"book_id"`
+func (h *Handler) BorrowBook(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, models.Transaction{}, "user", "book")
+	if err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-
 	var req BorrowRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := utils.ParseBody(r, &req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	db := models.GetDB()
+	userID, err := h.actingUser(r, req.UserID, models.PermLoansManage)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
 
-	user, _ := models.GetUserById(int64(req.UserID))
-	if user.ID == 0 {
-		http.Error(w, "User not found", http.StatusNotFound)
+	transaction, err := h.circulation.Checkout(userID, req.BookID, req.Barcode)
+	if err != nil {
+		apierror.Respond(w, circulationError(err))
 		return
 	}
+	h.writeTransaction(w, v, transaction)
+}
 
-	book, _ := models.GetBookById(int64(req.BookID))
-	if book.ID == 0 {
-		http.Error(w, "Book not found", http.StatusNotFound)
+// ReturnBook closes a loan and passes the book on to the next reservation, if any.
+func (h *Handler) ReturnBook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionIdStr := vars["transactionId"]
+	transactionID, err := strconv.ParseUint(transactionIdStr, 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid transaction ID"))
 		return
 	}
-	if book.Availability != "Available" {
-		http.Error(w, "Book is currently not available", http.StatusConflict)
+	v, err := parseView(r, models.Transaction{}, "user", "book")
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	var currentBorrows int64
-	db.Model(&models.Transaction{}).Where("user_id = ? AND return_date IS NULL", req.UserID).Count(&currentBorrows)
-
-	if currentBorrows >= maxBorrowLimit {
-		errorMsg := fmt.Sprintf("Borrow limit of %d books reached", maxBorrowLimit)
-		http.Error(w, errorMsg, http.StatusForbidden)
+	claims, err := caller(r)
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
-
-	transaction := models.Transaction{
-		UserID:     req.UserID,
-		BookID:     req.BookID,
-		BorrowDate: time.Now(),
-		DueDate:    time.Now().AddDate(0, 0, 14),
-	}
-	if result := db.Create(&transaction); result.Error != nil {
-		http.Error(w, "Failed to create transaction: "+result.Error.Error(), http.StatusInternalServerError)
+	loan, err := h.store.Transactions().FindByID(uint(transactionID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "Transaction not found"))
 		return
 	}
+	if loan.UserID != claims.UserID {
+		if err := require(h.store, claims, models.PermLoansManage, "Returning another user's loan"); err != nil {
+			apierror.Respond(w, err)
+			return
+		}
+	}
 
-	book.Availability = "Borrowed"
-	db.Save(&book)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(transaction)
+	transaction, err := h.circulation.Checkin(uint(transactionID))
+	if err != nil {
+		apierror.Respond(w, circulationError(err))
+		return
+	}
+	h.writeTransaction(w, v, transaction)
 }
 
-// ReturnBook is updated to handle reservations.
-func ReturnBook(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	transactionIdStr := vars["transactionId"]
-	transactionID, err := strconv.ParseUint(transactionIdStr, 10, 64)
+// MarkLoanLost closes a loan whose copy the borrower lost. The copy is marked
+// Lost and the loan stops counting toward the borrower's limit.
+func (h *Handler) MarkLoanLost(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := strconv.ParseUint(mux.Vars(r)["transactionId"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid transaction ID"))
 		return
 	}
-
-	db := models.GetDB()
-	var transaction models.Transaction
-	if result := db.First(&transaction, transactionID); result.Error != nil {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	v, err := parseView(r, models.Transaction{}, "user", "book")
+	if err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	if transaction.ReturnDate != nil {
-		http.Error(w, "Book has already been returned", http.StatusConflict)
+	transaction, err := h.circulation.MarkLost(uint(transactionID))
+	if err != nil {
+		apierror.Respond(w, circulationError(err))
 		return
 	}
+	h.writeTransaction(w, v, transaction)
+}
 
-	returnDate := time.Now()
-	transaction.ReturnDate = &returnDate
-
-	if returnDate.After(transaction.DueDate) {
-		daysOverdue := int(returnDate.Sub(transaction.DueDate).Hours() / 24)
-		transaction.Fine = float64(daysOverdue * 1)
+// writeTransaction writes a loan with the related records v includes.
+func (h *Handler) writeTransaction(w http.ResponseWriter, v view, transaction *models.Transaction) {
+	var err error
+	transaction.User, transaction.Book, err = h.includeUserAndBook(v, transaction.UserID, transaction.BookID)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-	db.Save(&transaction)
-
-	// --- NEW LOGIC: Check for reservations when a book is returned ---
-	var reservation models.Reservation
-	// Find the oldest pending reservation for this book
-	result := db.Where("book_id = ? AND status = ?", transaction.BookID, "Pending").Order("created_at asc").First(&reservation)
-
-	book, _ := models.GetBookById(int64(transaction.BookID))
-
-	if result.Error == nil {
-		// A reservation was found, so fulfill it.
-		reservation.Status = "Fulfilled"
-		db.Save(&reservation)
-		// Set the book's status to "Reserved" for the next user.
-		book.Availability = "Reserved"
-	} else {
-		// No reservations found, make the book generally available.
-		book.Availability = "Available"
-	}
-	db.Save(&book)
-	// --- END OF NEW LOGIC ---
+	v.write(w, http.StatusOK, transaction)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(transaction)
+// circulationError maps the circulation service's errors onto API errors.
+func circulationError(err error) error {
+	switch {
+	case errors.Is(err, circulation.ErrUserNotFound):
+		return apierror.NotFound("User not found")
+	case errors.Is(err, circulation.ErrBookNotFound):
+		return apierror.NotFound("Book not found")
+	case errors.Is(err, circulation.ErrCopyNotFound):
+		return apierror.NotFound("Copy not found for this book")
+	case errors.Is(err, circulation.ErrTransactionNotFound):
+		return apierror.NotFound("Transaction not found")
+	case errors.Is(err, circulation.ErrBookUnavailable):
+		return apierror.Conflict(apierror.CodeBookUnavailable, "Book is currently not available")
+	case errors.Is(err, circulation.ErrCopyUnavailable):
+		return apierror.Conflict(apierror.CodeCopyUnavailable, "Copy is currently not available")
+	case errors.Is(err, circulation.ErrAlreadyReturned):
+		return apierror.Conflict(apierror.CodeAlreadyReturned, "Book has already been returned")
+	case errors.Is(err, circulation.ErrLoanLost):
+		return apierror.Conflict(apierror.CodeLoanLost, "Loan was closed because the book was lost")
+	case errors.Is(err, circulation.ErrBorrowLimit):
+		return apierror.New(http.StatusForbidden, apierror.CodeBorrowLimit, fmt.Sprintf("Borrow limit of %d books reached", circulation.MaxLoans))
+	default:
+		return err
+	}
 }
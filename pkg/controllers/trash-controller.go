@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"github.com/gorilla/mux"
+)
+
+// GetTrash lists the soft-deleted books, users or categories.
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	switch mux.Vars(r)["resource"] {
+	case "books":
+		listTrash(w, r, h.store.Books())
+	case "users":
+		listTrash(w, r, h.store.Users())
+	case "categories":
+		listTrash(w, r, h.store.Categories())
+	default:
+		apierror.Write(w, apierror.NotFound("Unknown trash resource"))
+	}
+}
+
+// RestoreFromTrash undoes the deletion of a book, user or category.
+func (h *Handler) RestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid ID"))
+		return
+	}
+
+	switch vars["resource"] {
+	case "books":
+		restoreFromTrash(w, h.store.Books(), uint(ID))
+	case "users":
+		restoreFromTrash(w, h.store.Users(), uint(ID))
+	case "categories":
+		restoreFromTrash(w, h.store.Categories(), uint(ID))
+	default:
+		apierror.Write(w, apierror.NotFound("Unknown trash resource"))
+	}
+}
+
+// PurgeResponse reports what POST /trash/purge removed.
+type PurgeResponse struct {
+	Purged        repository.PurgeResult `json:"purged"`
+	DeletedBefore time.Time              `json:"deleted_before"`
+}
+
+// PurgeTrash permanently removes every record that has been in the trash
+// longer than the configured retention period.
+func (h *Handler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	cutoff := time.Now().Add(-h.trash.Retention)
+	result, err := repository.PurgeTrash(h.store, cutoff)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PurgeResponse{Purged: result, DeletedBefore: cutoff})
+}
+
+func listTrash[T any](w http.ResponseWriter, r *http.Request, trash repository.Trash[T]) {
+	var zero T
+	v, err := parseView(r, zero)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	rows, err := trash.ListDeleted()
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	v.write(w, http.StatusOK, rows)
+}
+
+func restoreFromTrash[T any](w http.ResponseWriter, trash repository.Trash[T], id uint) {
+	row, err := trash.Restore(id)
+	switch {
+	case errors.Is(err, repository.ErrDuplicate):
+		apierror.Write(w, apierror.Conflict(apierror.CodeDuplicate, "Cannot restore: another record now uses the same unique value"))
+		return
+	case err != nil:
+		apierror.Respond(w, notFound(err, "Record not found in trash"))
+		return
+	}
+
+	res, _ := json.Marshal(row)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
@@ -2,132 +2,313 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
 	"github.com/J-Mihir/go-bookstore/pkg/utils"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
 	"github.com/gorilla/mux"
 )
 
 var NewUser models.User
 
-func GetUser(w http.ResponseWriter, r *http.Request) {
-	newUsers := models.GetAllUsers()
-	res, _ := json.Marshal(newUsers)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+// GetUser retrieves a page of users, optionally filtered and sorted.
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	v, err := parseView(r, models.User{})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	q, p, err := parseListQuery(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	newUsers, total, err := h.store.Users().List(q)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	writeList(w, r, v, newUsers, total, p)
 }
 
-func GetUserById(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetUserById(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 	ID, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
-		fmt.Println("error while parsing")
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid user ID"))
 		return
 	}
-	userDetails, _ := models.GetUserById(ID)
-	if userDetails.ID == 0 {
-		w.WriteHeader(http.StatusNotFound)
+	if err := h.authorizeUser(r, uint(ID), models.PermUsersRead); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
-	res, _ := json.Marshal(userDetails)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+	v, err := parseView(r, models.User{})
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	userDetails, err := h.store.Users().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "User not found"))
+		return
+	}
+	writeResource(w, r, v, userDetails)
+}
+
+// UserRequest is the body of POST /register and POST /users. It has only the
+// fields a client may set, so the ID, timestamps and trash state of a new
+// user cannot come from the request.
+type UserRequest struct {
+	Name         string  `json:"name" validate:"required"`
+	Email        string  `json:"email" validate:"required,email"`
+	Password     string  `json:"password" validate:"required,min=8"`
+	MembershipID string  `json:"membership_id" validate:"required"`
+	Role         string  `json:"role"`
+	Fines        float64 `json:"fines" validate:"min=0"`
 }
 
-func CreateUser(w http.ResponseWriter, r *http.Request) {
-	newUser := &models.User{}
-	utils.ParseBody(r, newUser)
+func (req *UserRequest) user() *models.User {
+	return &models.User{
+		Name:         req.Name,
+		Email:        req.Email,
+		Password:     req.Password,
+		MembershipID: req.MembershipID,
+		Role:         req.Role,
+		Fines:        req.Fines,
+	}
+}
+
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	req := &UserRequest{}
+	if err := utils.ParseBody(r, req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	newUser := req.user()
+	if newUser.Role == "" {
+		newUser.Role = models.DefaultRole
+	}
+	claims, err := caller(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if err := checkRoleAssignment(h.store, claims, newUser.Role); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
 
-	// Call the updated CreateUser method which returns an error
-	u, err := newUser.CreateUser()
+	err = h.store.Users().Create(newUser)
 
 	// If there was an error (e.g., duplicate email), send a 409 Conflict response
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict) // 409 Conflict
-		json.NewEncoder(w).Encode(map[string]string{"error": "User with this email or membership ID already exists."})
+		apierror.Respond(w, duplicateUser(err))
 		return
 	}
 
 	// If successful, send the created user
-	res, _ := json.Marshal(u)
+	res, _ := json.Marshal(newUser)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 	ID, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
-		fmt.Println("error while parsing")
+		apierror.Write(w, apierror.BadRequest("Invalid user ID"))
+		return
+	}
+	var user *models.User
+	err = h.store.Atomic(func(tx repository.Store) error {
+		current, err := tx.Users().FindForUpdate(uint(ID))
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, current); err != nil {
+			return err
+		}
+		if current.Role == models.AdminRole {
+			if err := checkAdminsRemain(tx); err != nil {
+				return err
+			}
+		}
+		if user, err = tx.Users().Delete(uint(ID)); err != nil {
+			return err
+		}
+		// A deleted user's sessions end with it.
+		return tx.RefreshTokens().RevokeUser(uint(ID), time.Now())
+	})
+	if err != nil {
+		apierror.Respond(w, notFound(err, "User not found"))
+		return
 	}
-	user := models.DeleteUser(ID)
 	res, _ := json.Marshal(user)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(res)
 }
 
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	// First, get the user we want to update from the database
+// UpdateUser replaces a user's profile with the request body; omitted fields
+// are cleared. See saveUser.
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 	ID, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
-		fmt.Println("error while parsing userId")
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.Write(w, apierror.BadRequest("Invalid user ID"))
+		return
+	}
+	if err := h.authorizeUser(r, uint(ID), models.PermUsersWrite); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	userDetails, db := models.GetUserById(ID)
-	if userDetails.ID == 0 {
-		w.WriteHeader(http.StatusNotFound)
+	replacement := &models.User{}
+	if err := utils.ParseBody(r, replacement); err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	if replacement.Password != "" {
+		apierror.Write(w, errPasswordReadOnly)
 		return
 	}
+	h.saveUser(w, r, uint(ID), replacement)
+}
 
-	// Now, parse the incoming JSON into a temporary map
-	// This allows us to check which fields were actually sent in the request
-	var updateData map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+// PatchUser applies a JSON merge patch to a user's profile.
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+	ID, err := strconv.ParseInt(userId, 10, 64)
+	if err != nil {
+		apierror.Write(w, apierror.BadRequest("Invalid user ID"))
+		return
+	}
+	if err := h.authorizeUser(r, uint(ID), models.PermUsersWrite); err != nil {
+		apierror.Respond(w, err)
 		return
 	}
 
-	// Check for each field in the map and update if it exists
-	if name, ok := updateData["name"].(string); ok {
-		userDetails.Name = name
+	current, err := h.store.Users().FindByID(uint(ID))
+	if err != nil {
+		apierror.Respond(w, notFound(err, "User not found"))
+		return
 	}
-	if email, ok := updateData["email"].(string); ok {
-		userDetails.Email = email
+	patched := &models.User{}
+	if err := utils.ParseMergePatch(r, current, patched); err != nil {
+		apierror.Respond(w, err)
+		return
 	}
-	if membershipID, ok := updateData["membership_id"].(string); ok {
-		userDetails.MembershipID = membershipID
+	// The password is never encoded, so the patch is the only way to set one.
+	if patched.Password != "" {
+		apierror.Write(w, errPasswordReadOnly)
+		return
 	}
-	if role, ok := updateData["role"].(string); ok {
-		userDetails.Role = role
+	h.saveUser(w, r, uint(ID), patched)
+}
+
+// errPasswordReadOnly rejects password changes through the profile endpoints.
+var errPasswordReadOnly = apierror.Validation(apierror.FieldError{
+	Field: "password", Code: "read_only", Message: "password cannot be changed here; use PUT /me/password",
+})
+
+// saveUser gives the user with the given ID the profile fields of
+// replacement: name, email, membership_id, role and fines. Changing the
+// last three needs users:write, checkRoleAssignment and fines:waive
+// respectively, and changing someone else needs checkOutranks. A new role
+// ends the user's sessions, so it applies at once. A stale If-Match fails
+// with 412.
+func (h *Handler) saveUser(w http.ResponseWriter, r *http.Request, id uint, replacement *models.User) {
+	claims, err := caller(r)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
 	}
+	err = h.store.Atomic(func(tx repository.Store) error {
+		userDetails, err := tx.Users().FindForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r, userDetails); err != nil {
+			return err
+		}
+		if err := checkOutranks(tx, claims, userDetails); err != nil {
+			return err
+		}
+		if replacement.Role == "" {
+			replacement.Role = models.DefaultRole
+		}
+		if replacement.MembershipID != userDetails.MembershipID || replacement.Role != userDetails.Role {
+			if err := require(tx, claims, models.PermUsersWrite, "Changing membership_id or role"); err != nil {
+				return err
+			}
+		}
+		if replacement.Role != userDetails.Role {
+			if err := checkRoleAssignment(tx, claims, replacement.Role); err != nil {
+				return err
+			}
+			if userDetails.Role == models.AdminRole {
+				if err := checkAdminsRemain(tx); err != nil {
+					return err
+				}
+			}
+		}
+		if replacement.Fines != userDetails.Fines {
+			if err := require(tx, claims, models.PermFinesWaive, "Changing fines"); err != nil {
+				return err
+			}
+		}
 
-	// This is the key fix: We check if "fines" exists in the request.
-	// This works even if the value is 0.
-	if fines, ok := updateData["fines"].(float64); ok {
-		userDetails.Fines = fines
+		roleChanged := replacement.Role != userDetails.Role
+		userDetails.Name = replacement.Name
+		userDetails.Email = replacement.Email
+		userDetails.MembershipID = replacement.MembershipID
+		userDetails.Role = replacement.Role
+		userDetails.Fines = replacement.Fines
+		if err := validate.Struct(userDetails); err != nil {
+			return err
+		}
+		if err := tx.Users().Update(userDetails); err != nil {
+			return err
+		}
+		if roleChanged {
+			// Tokens carry the role, so sign the user in again with the new one.
+			return tx.RefreshTokens().RevokeUser(id, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		apierror.Respond(w, duplicateUser(notFound(err, "User not found")))
+		return
 	}
 
-	// Save the changes to the database
-	db.Save(&userDetails)
+	// Reload so the ETag matches what a later GET returns.
+	userDetails, err := h.store.Users().FindByID(id)
+	if err != nil {
+		apierror.Respond(w, err)
+		return
+	}
+	writeResource(w, r, view{}, userDetails)
+}
 
-	// Return the updated user details
-	res, _ := json.Marshal(userDetails)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(res)
+// duplicateUser explains a duplicate error on a user write.
+func duplicateUser(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict(apierror.CodeDuplicate, "User with this email or membership ID already exists")
+	}
+	return err
 }
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// view shapes a response: ?include=user,book embeds related records and
+// ?fields=name,isbn keeps only the named top-level fields of each record.
+type view struct {
+	include map[string]bool
+	// fields is nil when every field is kept.
+	fields map[string]bool
+}
+
+// parseView reads ?include= and ?fields= for a response made of records like
+// resource. includable lists the related records the endpoint can embed.
+// Unknown names are a 400. ID and the included records are always kept.
+func parseView(r *http.Request, resource any, includable ...string) (view, error) {
+	v := view{include: map[string]bool{}}
+	params := r.URL.Query()
+
+	for _, name := range splitList(params.Get("include")) {
+		if !slices.Contains(includable, name) {
+			if len(includable) == 0 {
+				return v, invalidQuery("include is not supported here")
+			}
+			return v, invalidQuery(fmt.Sprintf("include must be a comma-separated list of: %s", strings.Join(includable, ", ")))
+		}
+		v.include[name] = true
+	}
+
+	if params.Get("fields") != "" {
+		known := jsonFields(reflect.TypeOf(resource))
+		v.fields = map[string]bool{"ID": true}
+		for _, name := range splitList(params.Get("fields")) {
+			if !known[name] {
+				return v, invalidQuery(fmt.Sprintf("Unknown field %q in fields", name))
+			}
+			v.fields[name] = true
+		}
+		for name := range v.include {
+			v.fields[name] = true
+		}
+	}
+	return v, nil
+}
+
+// render returns the JSON encoding of data, which is a record or a slice of
+// records, keeping only the fields the view selects.
+func (v view) render(data any) []byte {
+	res, _ := json.Marshal(data)
+	if v.fields == nil {
+		return res
+	}
+	if bytes.HasPrefix(res, []byte("[")) {
+		var items []json.RawMessage
+		json.Unmarshal(res, &items)
+		for i, item := range items {
+			items[i] = v.selectFields(item)
+		}
+		res, _ = json.Marshal(items)
+		return res
+	}
+	return v.selectFields(res)
+}
+
+// write sends data as the JSON response body with the given status.
+func (v view) write(w http.ResponseWriter, status int, data any) {
+	res := v.render(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(res)
+}
+
+// selectFields drops the members of a JSON object the view does not keep,
+// leaving the rest in their original order.
+func (v view) selectFields(object []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return object
+	}
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return object
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return object
+		}
+		if key, _ := t.(string); v.fields[key] {
+			if out.Len() > 1 {
+				out.WriteByte(',')
+			}
+			name, _ := json.Marshal(key)
+			out.Write(name)
+			out.WriteByte(':')
+			out.Write(value)
+		}
+	}
+	out.WriteByte('}')
+	return out.Bytes()
+}
+
+// jsonFields returns the JSON member names of a struct type, looking through
+// pointers, slices and embedded structs.
+func jsonFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded := range jsonFields(field.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// splitList splits a comma-separated query parameter, dropping blanks.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func invalidQuery(message string) *apierror.Error {
+	return apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, message)
+}
+
+// includeCategories sets the Category of each book, loading each category once.
+func (h *Handler) includeCategories(books ...*models.Book) error {
+	categories := map[uint]*models.Category{}
+	for _, book := range books {
+		category, ok := categories[book.CategoryID]
+		if !ok {
+			var err error
+			if category, err = related(h.store.Categories().FindByID(book.CategoryID)); err != nil {
+				return err
+			}
+			categories[book.CategoryID] = category
+		}
+		book.Category = category
+	}
+	return nil
+}
+
+// includeUserAndBook loads the user and book a loan or reservation refers
+// to, each only if the view includes it.
+func (h *Handler) includeUserAndBook(v view, userID, bookID uint) (user *models.User, book *models.Book, err error) {
+	if v.include["user"] {
+		if user, err = related(h.store.Users().FindByID(userID)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if v.include["book"] {
+		if book, err = related(h.store.Books().FindByID(bookID)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, book, nil
+}
+
+// related passes on a related record, treating one that no longer exists
+// (e.g. it is in the trash) as absent rather than as an error.
+func related[T any](record *T, err error) (*T, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return record, err
+}
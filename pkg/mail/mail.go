@@ -0,0 +1,118 @@
+// Package mail sends email, such as password reset links. The Sender is
+// chosen by configuration: SMTP in production, or a log or file sink that
+// keeps messages on the machine for local use.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+)
+
+// Message is a plain-text email to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email.
+type Sender interface {
+	Send(m Message) error
+}
+
+// FromConfig returns the configured Sender.
+func FromConfig(cfg config.MailConfig) (Sender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("mail.from: %w", err)
+	}
+	switch cfg.Sender {
+	case config.MailLog, "":
+		return logSender{from: from}, nil
+	case config.MailFile:
+		return &fileSender{from: from, path: cfg.File}, nil
+	case config.MailSMTP:
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			return nil, fmt.Errorf("mail.smtp_addr: %w", err)
+		}
+		s := smtpSender{from: from, addr: cfg.SMTPAddr}
+		if cfg.SMTPUsername != "" {
+			s.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", cfg.Sender)
+	}
+}
+
+// logSender writes every message to the server log.
+type logSender struct {
+	from *mail.Address
+}
+
+func (s logSender) Send(m Message) error {
+	log.Printf("mail: not sending email, logging it instead\n%s", format(s.from, m))
+	return nil
+}
+
+// fileSender appends every message to a file, separated by blank lines.
+type fileSender struct {
+	from *mail.Address
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileSender) Send(m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	if _, err := f.Write(append(format(s.from, m), "\r\n"...)); err != nil {
+		f.Close()
+		return fmt.Errorf("mail: %w", err)
+	}
+	return f.Close()
+}
+
+// smtpSender delivers messages through an SMTP server, using STARTTLS when
+// the server offers it.
+type smtpSender struct {
+	from *mail.Address
+	addr string
+	auth smtp.Auth
+}
+
+func (s smtpSender) Send(m Message) error {
+	if err := smtp.SendMail(s.addr, s.auth, s.from.Address, []string{m.To}, format(s.from, m)); err != nil {
+		return fmt.Errorf("mail: sending to %s: %w", m.To, err)
+	}
+	return nil
+}
+
+// format renders m as an RFC 5322 message.
+func format(from *mail.Address, m Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,55 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7396). A patch
+// object lists the members to change: a value replaces the member, null
+// removes it, and nested objects are merged recursively. Any other patch
+// value replaces the whole document.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Apply returns doc with patch applied. Both must be valid JSON; the error
+// from decoding patch is returned unchanged so callers can report it.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+// decode unmarshals data keeping numbers as json.Number, so values that are
+// not changed by the patch come out exactly as they went in.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = merge(t[name], value)
+		}
+	}
+	return t
+}
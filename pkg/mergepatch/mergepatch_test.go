@@ -0,0 +1,60 @@
+package mergepatch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/mergepatch"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		// The examples of RFC 7396, Appendix A.
+		{"replace", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"remove one of two", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"array replaces value", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"nested", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"arrays are not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"array document", `["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{"object replaces array", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"null document", `{"a":"foo"}`, `null`, `null`},
+		{"string document", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"null in a new member", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{"object into a non-object", `[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{"nested nulls are removed", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+
+		{"numbers are kept exactly", `{"id":12345678901234567890,"fine":0.10}`, `{"name":"x"}`, `{"fine":0.10,"id":12345678901234567890,"name":"x"}`},
+		{"empty patch", `{"a":{"b":1}}`, `{}`, `{"a":{"b":1}}`},
+		{"nested object replaces scalar", `{"a":1}`, `{"a":{"b":2}}`, `{"a":{"b":2}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergepatch.Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, []byte(tt.want)) {
+				t.Errorf("Apply(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyReturnsDecodeErrors(t *testing.T) {
+	if _, err := mergepatch.Apply([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Error("Apply() accepted a malformed patch")
+	}
+	_, err := mergepatch.Apply([]byte(`{}`), []byte(`{"a":tru}`))
+	if _, ok := err.(*json.SyntaxError); !ok {
+		t.Errorf("Apply() = %T %v, want the *json.SyntaxError unchanged", err, err)
+	}
+	if _, err := mergepatch.Apply([]byte(`{"a"`), []byte(`{}`)); err == nil {
+		t.Error("Apply() accepted a malformed document")
+	}
+}
@@ -2,27 +2,28 @@ package middleware
 
 import (
 	"context"
-	"fmt" // Import fmt for logging
+	"errors"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtKey []byte
-
-// We'll use an init function here as well to ensure both files see the same key.
-func init() {
-	secret := os.Getenv("JWT_SECRET_KEY")
-	// --- DIAGNOSTIC LOG ---
-	// This will print the key your middleware is using to the terminal on startup.
-	fmt.Printf("INFO: Middleware JWT_SECRET_KEY being used: '%s'\n", secret)
-	if secret == "" {
-		fmt.Println("WARNING: Middleware JWT_SECRET_KEY not set. Using default.")
-		secret = "default_insecure_secret_key"
-	}
-	jwtKey = []byte(secret)
+// Auth authenticates requests with JWTs and checks the caller's permissions.
+// Each App builds its own from its configuration and store.
+type Auth struct {
+	// SigningKey verifies token signatures.
+	SigningKey []byte
+	// Revoked reports whether the session of a valid token has ended, so that
+	// logged-out sessions stop working before their access tokens expire.
+	// When nil, every valid token is accepted.
+	Revoked func(*Claims) (bool, error)
+	// Permissions returns the permissions a role grants. It is called on
+	// every check, so changes to a role apply at once to everyone holding it.
+	// When nil, no role grants anything.
+	Permissions func(role string) ([]string, error)
 }
 
 type Claims struct {
@@ -31,16 +32,28 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// ClaimsFrom returns the claims JWTMiddleware added to ctx.
+func ClaimsFrom(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(userContextKey).(*Claims)
+	if !ok {
+		return nil, errMissingClaims
+	}
+	return claims, nil
+}
+
+// errMissingClaims means a handler requiring claims was not wrapped in JWTMiddleware.
+var errMissingClaims = errors.New("could not retrieve user claims")
+
 type contextKey string
 
 const userContextKey = contextKey("user")
 
 // JWTMiddleware validates the token and adds user claims to the request context.
-func JWTMiddleware(next http.Handler) http.Handler {
+func (a *Auth) JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+			apierror.Write(w, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Missing authorization header"))
 			return
 		}
 
@@ -48,33 +61,69 @@ func JWTMiddleware(next http.Handler) http.Handler {
 		claims := &Claims{}
 
 		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
+			return a.SigningKey, nil
 		})
 
 		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			apierror.Write(w, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired token"))
 			return
 		}
+		if a.Revoked != nil {
+			isRevoked, err := a.Revoked(claims)
+			if err != nil {
+				apierror.Write(w, apierror.Internal(err))
+				return
+			}
+			if isRevoked {
+				apierror.Write(w, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Token has been revoked"))
+				return
+			}
+		}
 
 		ctx := context.WithValue(r.Context(), userContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// AdminRequired checks the role from the JWT claims in the context.
-func AdminRequired(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims, ok := r.Context().Value(userContextKey).(*Claims)
-		if !ok {
-			http.Error(w, "Could not retrieve user claims", http.StatusInternalServerError)
-			return
+// HasPermission reports whether the role in claims grants any of perms.
+func (a *Auth) HasPermission(claims *Claims, perms ...string) (bool, error) {
+	if a.Permissions == nil {
+		return false, nil
+	}
+	granted, err := a.Permissions(claims.Role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if slices.Contains(granted, p) {
+			return true, nil
 		}
+	}
+	return false, nil
+}
 
-		if claims.Role != "staff" {
-			http.Error(w, "Forbidden: Admin access required", http.StatusForbidden)
-			return
-		}
+// RequirePermission lets a request through only if the caller's role grants
+// any of perms. It must run after JWTMiddleware.
+func (a *Auth) RequirePermission(perms ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := ClaimsFrom(r.Context())
+			if err != nil {
+				apierror.Write(w, apierror.Internal(err))
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			ok, err := a.HasPermission(claims, perms...)
+			if err != nil {
+				apierror.Write(w, apierror.Internal(err))
+				return
+			}
+			if !ok {
+				apierror.Write(w, apierror.Forbidden("Missing permission: "+strings.Join(perms, " or ")))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
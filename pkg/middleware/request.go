@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+)
+
+// validRequestID limits the request IDs accepted from clients and proxies.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID gives every request an ID, reusing a well-formed X-Request-ID from
+// the client or a proxy. The ID is echoed in the X-Request-ID response header
+// and included in error responses.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(apierror.HeaderRequestID)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+			r.Header.Set(apierror.HeaderRequestID, id)
+		}
+		w.Header().Set(apierror.HeaderRequestID, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Recover turns a panicking handler into a logged 500 error response.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, debug.Stack())
+				apierror.Write(w, apierror.Internal(fmt.Errorf("panic: %v", v)))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitBody caps request bodies at n bytes. Reading past the limit fails with
+// *http.MaxBytesError, which utils.ParseBody reports as 413.
+func LimitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Deprecated marks responses as coming from a deprecated path (RFC 9745 and
+// RFC 8594): Deprecation and Sunset carry the given dates, and a Link header
+// points to the same path under successorPrefix, e.g. /api/v1.
+func Deprecated(successorPrefix string, deprecated, sunset time.Time) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", deprecated.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successorPrefix+r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The structs below are snapshots of the models as of this migration.
+// They must not be changed; later schema changes belong in new migrations.
+
+type user0001 struct {
+	gorm.Model
+	Name         string
+	Email        string `gorm:"unique"`
+	Password     string
+	MembershipID string `gorm:"unique"`
+	Role         string
+	Fines        float64
+}
+
+func (user0001) TableName() string { return "users" }
+
+type category0001 struct {
+	gorm.Model
+	Name string `gorm:"unique"`
+}
+
+func (category0001) TableName() string { return "categories" }
+
+type book0001 struct {
+	gorm.Model
+	Name         string
+	Author       string
+	Publication  string
+	ISBN         string `gorm:"unique"`
+	Genre        string
+	Edition      string
+	Copies       int
+	Availability string
+	CategoryID   uint
+}
+
+func (book0001) TableName() string { return "books" }
+
+type transaction0001 struct {
+	gorm.Model
+	UserID     uint
+	BookID     uint
+	BorrowDate time.Time
+	DueDate    time.Time
+	ReturnDate *time.Time
+	Fine       float64
+}
+
+func (transaction0001) TableName() string { return "transactions" }
+
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		return createTables(tx, &user0001{}, &category0001{}, &book0001{}, &transaction0001{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTables(tx, &transaction0001{}, &book0001{}, &category0001{}, &user0001{})
+	},
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type reservation0002 struct {
+	gorm.Model
+	UserID uint
+	BookID uint
+	Status string
+}
+
+func (reservation0002) TableName() string { return "reservations" }
+
+// createReservations adds the table AutoMigrate never created for the Reservation model.
+var createReservations = Migration{
+	Version: 2,
+	Name:    "create_reservations",
+	Up: func(tx *gorm.DB) error {
+		return createTables(tx, &reservation0002{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTables(tx, &reservation0002{})
+	},
+}
@@ -0,0 +1,178 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type bookCopy0003 struct {
+	gorm.Model
+	BookID    uint   `gorm:"index"`
+	Barcode   string `gorm:"unique"`
+	Status    string
+	Location  string
+	Condition string
+}
+
+func (bookCopy0003) TableName() string { return "book_copies" }
+
+type transaction0003 struct {
+	gorm.Model
+	UserID     uint
+	BookID     uint
+	CopyID     uint
+	BorrowDate time.Time
+	DueDate    time.Time
+	ReturnDate *time.Time
+	Fine       float64
+}
+
+func (transaction0003) TableName() string { return "transactions" }
+
+type reservation0003 struct {
+	gorm.Model
+	UserID uint
+	BookID uint
+	Status string
+	CopyID *uint
+}
+
+func (reservation0003) TableName() string { return "reservations" }
+
+// bookCopies replaces the books.copies count and books.availability text with
+// one book_copies row per physical item. Existing books get their copies
+// generated, with open loans and held reservations assigned to specific copies.
+var bookCopies = Migration{
+	Version: 3,
+	Name:    "book_copies",
+	Up: func(tx *gorm.DB) error {
+		if err := createTables(tx, &bookCopy0003{}); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&transaction0003{}, "CopyID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&reservation0003{}, "CopyID"); err != nil {
+			return err
+		}
+
+		if err := generateCopies(tx); err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(&book0001{}, "Copies"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&book0001{}, "Availability")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&book0001{}, "Copies"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&book0001{}, "Availability"); err != nil {
+			return err
+		}
+
+		var books []book0001
+		if err := tx.Unscoped().Find(&books).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			var copies []bookCopy0003
+			if err := tx.Where("book_id = ?", book.ID).Find(&copies).Error; err != nil {
+				return err
+			}
+			availability := "Not Available"
+			counts := map[string]int{}
+			for _, c := range copies {
+				counts[c.Status]++
+			}
+			switch {
+			case counts["Available"] > 0:
+				availability = "Available"
+			case counts["Reserved"] > 0:
+				availability = "Reserved"
+			case counts["Borrowed"] > 0:
+				availability = "Borrowed"
+			}
+			err := tx.Model(&book0001{}).Unscoped().Where("id = ?", book.ID).
+				Updates(map[string]interface{}{"copies": len(copies), "availability": availability}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Migrator().DropColumn(&reservation0003{}, "CopyID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&transaction0003{}, "CopyID"); err != nil {
+			return err
+		}
+		return dropTables(tx, &bookCopy0003{})
+	},
+}
+
+// generateCopies creates the copies of every existing book from its copies
+// count, lending one copy to each open loan and holding one for a book that
+// was marked Reserved.
+func generateCopies(tx *gorm.DB) error {
+	var books []book0001
+	if err := tx.Unscoped().Find(&books).Error; err != nil {
+		return err
+	}
+
+	for _, book := range books {
+		var openLoans []transaction0003
+		if err := tx.Where("book_id = ? AND return_date IS NULL", book.ID).Order("id").Find(&openLoans).Error; err != nil {
+			return err
+		}
+
+		total := book.Copies
+		if total < len(openLoans) {
+			total = len(openLoans)
+		}
+		if book.Availability == "Reserved" && total == len(openLoans) {
+			total++
+		}
+
+		var reservation reservation0003
+		heldFor := tx.Where("book_id = ? AND status = ?", book.ID, "Fulfilled").Order("updated_at desc").Limit(1).Find(&reservation)
+		if heldFor.Error != nil {
+			return heldFor.Error
+		}
+
+		held := false
+		for n := 1; n <= total; n++ {
+			item := bookCopy0003{
+				BookID:  book.ID,
+				Barcode: fmt.Sprintf("BK%06d-%03d", book.ID, n),
+				Status:  "Available",
+			}
+			if n <= len(openLoans) {
+				item.Status = "Borrowed"
+			} else if book.Availability == "Reserved" && !held {
+				item.Status = "Reserved"
+				held = true
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+
+			switch {
+			case n <= len(openLoans):
+				err := tx.Model(&transaction0003{}).Where("id = ?", openLoans[n-1].ID).Update("copy_id", item.ID).Error
+				if err != nil {
+					return err
+				}
+			case item.Status == "Reserved" && reservation.ID != 0:
+				err := tx.Model(&reservation0003{}).Where("id = ?", reservation.ID).Update("copy_id", item.ID).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
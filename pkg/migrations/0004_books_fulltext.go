@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// booksFullText adds the FULLTEXT index used by the MySQL search backend.
+// Other databases search without a dedicated index, so it does nothing there.
+var booksFullText = Migration{
+	Version: 4,
+	Name:    "books_fulltext",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "mysql" {
+			return nil
+		}
+		return tx.Exec("CREATE FULLTEXT INDEX idx_books_fulltext ON books (name, author, publication, isbn, genre)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "mysql" {
+			return nil
+		}
+		return tx.Exec("DROP INDEX idx_books_fulltext ON books").Error
+	},
+}
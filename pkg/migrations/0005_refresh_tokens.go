@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshToken0005 struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	SessionID string `gorm:"size:64;index"`
+	TokenHash string `gorm:"size:64;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+func (refreshToken0005) TableName() string { return "refresh_tokens" }
+
+// refreshTokens stores the rotating refresh tokens of login sessions.
+var refreshTokens = Migration{
+	Version: 5,
+	Name:    "refresh_tokens",
+	Up: func(tx *gorm.DB) error {
+		return createTables(tx, &refreshToken0005{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTables(tx, &refreshToken0005{})
+	},
+}
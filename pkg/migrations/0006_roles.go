@@ -0,0 +1,76 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type role0006 struct {
+	gorm.Model
+	Name        string `gorm:"size:64;unique"`
+	Description string
+}
+
+func (role0006) TableName() string { return "roles" }
+
+type rolePermission0006 struct {
+	RoleID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	Permission string `gorm:"primaryKey;size:64"`
+}
+
+func (rolePermission0006) TableName() string { return "role_permissions" }
+
+// roles0006 are the roles the migration creates, with their permissions.
+var roles0006 = []struct {
+	name, description string
+	permissions       []string
+}{
+	{"admin", "Full access, including roles", []string{
+		"books:write", "categories:write", "users:read", "users:write", "users:delete", "fines:waive",
+		"loans:borrow", "loans:manage", "reservations:create", "reservations:manage", "trash:manage", "roles:manage",
+	}},
+	{"librarian", "Manages the catalog, members, circulation and the trash", []string{
+		"books:write", "categories:write", "users:read", "users:write", "fines:waive",
+		"loans:borrow", "loans:manage", "reservations:create", "reservations:manage", "trash:manage",
+	}},
+	{"circulation_clerk", "Lends and takes back books for members", []string{
+		"users:read", "loans:borrow", "loans:manage", "reservations:create", "reservations:manage",
+	}},
+	{"student", "Borrows and reserves books", []string{"loans:borrow", "reservations:create"}},
+	{"faculty", "Borrows and reserves books", []string{"loans:borrow", "reservations:create"}},
+	{"guest", "Browses the catalog", nil},
+}
+
+// roles replaces the staff/student role strings with roles stored in the
+// database. Staff become admins; students keep their role.
+var roles = Migration{
+	Version: 6,
+	Name:    "roles",
+	Up: func(tx *gorm.DB) error {
+		if err := createTables(tx, &role0006{}, &rolePermission0006{}); err != nil {
+			return err
+		}
+		for _, r := range roles0006 {
+			role := role0006{Name: r.name, Description: r.description}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			for _, p := range r.permissions {
+				if err := tx.Create(&rolePermission0006{RoleID: role.ID, Permission: p}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&user0001{}).Unscoped().Where("role = ?", "staff").Update("role", "admin").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		err := tx.Model(&user0001{}).Unscoped().Where("role IN ?", []string{"admin", "librarian", "circulation_clerk"}).
+			Update("role", "staff").Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&user0001{}).Unscoped().Where("role NOT IN ?", []string{"staff", "student"}).
+			Update("role", "student").Error
+		if err != nil {
+			return err
+		}
+		return dropTables(tx, &rolePermission0006{}, &role0006{})
+	},
+}
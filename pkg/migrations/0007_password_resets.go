@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordReset0007 struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (passwordReset0007) TableName() string { return "password_resets" }
+
+// passwordResets stores the single-use tokens of POST /password/forgot.
+var passwordResets = Migration{
+	Version: 7,
+	Name:    "password_resets",
+	Up: func(tx *gorm.DB) error {
+		return createTables(tx, &passwordReset0007{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTables(tx, &passwordReset0007{})
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type transaction0008 struct {
+	gorm.Model
+	UserID     uint
+	BookID     uint
+	CopyID     uint
+	BorrowDate time.Time
+	DueDate    time.Time
+	ReturnDate *time.Time
+	LostAt     *time.Time
+	Fine       float64
+}
+
+func (transaction0008) TableName() string { return "transactions" }
+
+// lostLoans records when a loan was closed because the borrower lost the copy.
+var lostLoans = Migration{
+	Version: 8,
+	Name:    "lost_loans",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&transaction0008{}, "LostAt")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&transaction0008{}, "LostAt")
+	},
+}
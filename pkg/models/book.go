@@ -1,53 +1,59 @@
 package models
 
 import (
-	"github.com/J-Mihir/go-bookstore/pkg/config"
 	"gorm.io/gorm"
 )
 
-var db *gorm.DB
+// Availability summarises whether a title can be borrowed. It is computed from
+// the statuses of the book's copies and is never taken from client input.
+type Availability string
+
+const (
+	Available    Availability = "Available"     // at least one copy is on the shelf
+	OnHold       Availability = "Reserved"      // no copy on the shelf, at least one held for a reservation
+	OnLoan       Availability = "Borrowed"      // every circulating copy is on loan
+	NotAvailable Availability = "Not Available" // no circulating copies
+)
 
 type Book struct {
 	gorm.Model
-	Name         string `json:"name"`
-	Author       string `json:"author"`
-	Publication  string `json:"publication"`
-	ISBN         string `json:"isbn" gorm:"unique"` // unique for each edition
-	Genre        string `json:"genre"`
-	Edition      string `json:"edition"`
-	Copies       int    `json:"copies"` // total copies in library
-	Availability string `json:"availability"`
-	CategoryID   uint   `json:"category_id"` // Available, Borrowed, Reserved
-}
-
-func init() {
-	config.Connect()
-	db = config.GetDB()
-	db.AutoMigrate(&User{}, &Transaction{}, &Category{}, &Book{})
+	Name        string `json:"name" validate:"required"`
+	Author      string `json:"author"`
+	Publication string `json:"publication"`
+	ISBN        string `json:"isbn" gorm:"unique" validate:"required,isbn"` // unique for each edition
+	Genre       string `json:"genre"`
+	Edition     string `json:"edition"`
+	CategoryID  uint   `json:"category_id" validate:"required"`
+	// Category is only filled in when a response includes it.
+	Category *Category `json:"category,omitempty" gorm:"-"`
+
+	// Inventory fields computed from the book's copies; read-only in the API.
+	Copies         int          `json:"copies" gorm:"-" validate:"min=0"` // total copies in library
+	AvailableCount int          `json:"available_count" gorm:"-"`
+	OnLoanCount    int          `json:"on_loan_count" gorm:"-"`
+	OnHoldCount    int          `json:"on_hold_count" gorm:"-"`
+	Availability   Availability `json:"availability" gorm:"-"`
 }
 
-func (b *Book) CreateBook() (*Book, error) {
-	result := GetDB().Create(&b)
-	if result.Error != nil {
-		return nil, result.Error
+// SetInventory fills the computed inventory fields from the number of the
+// book's copies in each status.
+func (b *Book) SetInventory(copiesByStatus map[CopyStatus]int) {
+	b.Copies = 0
+	for _, n := range copiesByStatus {
+		b.Copies += n
+	}
+	b.AvailableCount = copiesByStatus[CopyAvailable]
+	b.OnLoanCount = copiesByStatus[CopyBorrowed]
+	b.OnHoldCount = copiesByStatus[CopyReserved]
+
+	switch {
+	case b.AvailableCount > 0:
+		b.Availability = Available
+	case b.OnHoldCount > 0:
+		b.Availability = OnHold
+	case b.OnLoanCount > 0:
+		b.Availability = OnLoan
+	default:
+		b.Availability = NotAvailable
 	}
-	return b, nil
-}
-
-func GetAllBooks() []Book {
-	var Books []Book
-	db.Find(&Books)
-	return Books
-}
-
-func GetBookById(Id int64) (*Book, *gorm.DB) {
-	var getBook Book
-	result := db.Where("id = ?", Id).First(&getBook)
-	return &getBook, result
-}
-
-func DeleteBook(Id int64) Book {
-	var book Book
-	db.Where("id = ?", Id).Delete(&book)
-	return book
 }
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// CopyStatus is the circulation state of a single BookCopy.
+type CopyStatus string
+
+const (
+	CopyAvailable CopyStatus = "Available" // on the shelf and can be borrowed
+	CopyBorrowed  CopyStatus = "Borrowed"  // on loan to a user
+	CopyReserved  CopyStatus = "Reserved"  // held for the user whose reservation was fulfilled
+	CopyLost      CopyStatus = "Lost"
+	CopyWithdrawn CopyStatus = "Withdrawn" // removed from circulation, e.g. too damaged to lend
+)
+
+// copyTransitions lists the statuses each status may move to.
+var copyTransitions = map[CopyStatus][]CopyStatus{
+	CopyAvailable: {CopyBorrowed, CopyReserved, CopyLost, CopyWithdrawn},
+	CopyBorrowed:  {CopyAvailable, CopyReserved, CopyLost},
+	CopyReserved:  {CopyBorrowed, CopyAvailable},
+	CopyLost:      {CopyAvailable, CopyWithdrawn},
+	CopyWithdrawn: {CopyAvailable},
+}
+
+// ParseCopyStatus validates s as a CopyStatus.
+func ParseCopyStatus(s string) (CopyStatus, error) {
+	status := CopyStatus(s)
+	if _, ok := copyTransitions[status]; !ok {
+		return "", fmt.Errorf("unknown copy status %q", s)
+	}
+	return status, nil
+}
+
+// CanTransitionTo reports whether a copy in status s may move to next.
+func (s CopyStatus) CanTransitionTo(next CopyStatus) bool {
+	for _, allowed := range copyTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidTransitionError is returned when a copy is moved to a status its
+// current status does not allow.
+type InvalidTransitionError struct {
+	From, To CopyStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("a %s copy cannot become %s", e.From, e.To)
+}
+
+// BookCopy is a single physical item of a Book that the library owns.
+type BookCopy struct {
+	gorm.Model
+	BookID    uint       `json:"book_id" gorm:"index"`
+	Barcode   string     `json:"barcode" gorm:"unique"`
+	Status    CopyStatus `json:"status"`
+	Location  string     `json:"location"`  // shelf or branch, e.g. "Main Library, Shelf C3"
+	Condition string     `json:"condition"` // e.g. "New", "Good", "Worn"
+}
+
+// TransitionTo moves the copy to next, or returns an *InvalidTransitionError
+// if the current status does not allow it.
+func (c *BookCopy) TransitionTo(next CopyStatus) error {
+	if !c.Status.CanTransitionTo(next) {
+		return &InvalidTransitionError{From: c.Status, To: next}
+	}
+	c.Status = next
+	return nil
+}
+
+// GenerateBarcode returns the default barcode for the n-th copy of a book.
+func GenerateBarcode(bookID uint, n int) string {
+	return fmt.Sprintf("BK%06d-%03d", bookID, n)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordReset is a single-use token, sent by email, that lets a user set a
+// new password without the old one. Only a SHA-256 hash of the token is stored.
+type PasswordReset struct {
+	gorm.Model
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"size:64;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
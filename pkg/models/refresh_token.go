@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one refresh token of a login session. Only a SHA-256 hash
+// of the token is stored. Refreshing marks the token used and issues the next
+// one in the same session; a used token that is presented again has leaked,
+// so the whole session is revoked.
+type RefreshToken struct {
+	gorm.Model
+	UserID uint `gorm:"index"`
+	// SessionID is shared by every token of one login and is the jti of the
+	// access tokens issued with them.
+	SessionID string `gorm:"size:64;index"`
+	TokenHash string `gorm:"size:64;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
@@ -8,8 +8,10 @@ import (
 type Reservation struct {
 	gorm.Model
 	UserID uint   `json:"user_id"`
-	User   User   `json:"user,omitempty"`
+	User   *User  `json:"user,omitempty"` // only filled in when a response includes it
 	BookID uint   `json:"book_id"`
-	Book   Book   `json:"book,omitempty"`
-	Status string `json:"status"` // e.g., "Pending", "Fulfilled", "Cancelled"
+	Book   *Book  `json:"book,omitempty"` // only filled in when a response includes it
+	Status string `json:"status"`         // e.g., "Pending", "Fulfilled", "Collected", "Cancelled"
+	// CopyID is the copy held for the user once the reservation is fulfilled.
+	CopyID *uint `json:"copy_id,omitempty"`
 }
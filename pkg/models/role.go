@@ -0,0 +1,88 @@
+package models
+
+import "gorm.io/gorm"
+
+// Permissions that roles grant. Handlers and routes check these, never role
+// names, so what a role may do is decided by the rows in role_permissions.
+const (
+	PermBooksWrite         = "books:write"
+	PermCategoriesWrite    = "categories:write"
+	PermUsersRead          = "users:read"
+	PermUsersWrite         = "users:write"
+	PermUsersDelete        = "users:delete"
+	PermFinesWaive         = "fines:waive"
+	PermLoansBorrow        = "loans:borrow"
+	PermLoansManage        = "loans:manage"
+	PermReservationsCreate = "reservations:create"
+	PermReservationsManage = "reservations:manage"
+	PermTrashManage        = "trash:manage"
+	PermRolesManage        = "roles:manage"
+)
+
+// Permission describes one permission for GET /permissions.
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions is every permission a role can grant.
+var Permissions = []Permission{
+	{PermBooksWrite, "Create, update and delete books and their copies"},
+	{PermCategoriesWrite, "Create, update and delete categories"},
+	{PermUsersRead, "View any user"},
+	{PermUsersWrite, "Create users and edit any user's profile and membership ID"},
+	{PermUsersDelete, "Delete users"},
+	{PermFinesWaive, "Change a user's outstanding fines"},
+	{PermLoansBorrow, "Borrow and return books for oneself"},
+	{PermLoansManage, "Lend and take back books for any user"},
+	{PermReservationsCreate, "Reserve books for oneself"},
+	{PermReservationsManage, "Reserve books for any user"},
+	{PermTrashManage, "List, restore and purge deleted records"},
+	{PermRolesManage, "Manage roles and assign any role to a user"},
+}
+
+// SelfServicePermissions only let users act for themselves. Roles granting
+// nothing else can be chosen at registration.
+var SelfServicePermissions = []string{PermLoansBorrow, PermReservationsCreate}
+
+// AdminRole always grants every permission and cannot be changed or deleted,
+// so the library can never lock itself out.
+const AdminRole = "admin"
+
+// DefaultRole is given to users registered without a role.
+const DefaultRole = "student"
+
+// Role is a named set of permissions. Users refer to their role by name.
+type Role struct {
+	gorm.Model
+	Name        string   `json:"name" gorm:"size:64;unique" validate:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" gorm:"-"`
+}
+
+// RolePermission grants one permission to a role.
+type RolePermission struct {
+	RoleID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	Permission string `gorm:"primaryKey;size:64"`
+}
+
+// DefaultRoles are the roles a new library starts with.
+func DefaultRoles() []Role {
+	all := make([]string, len(Permissions))
+	for i, p := range Permissions {
+		all[i] = p.Name
+	}
+	return []Role{
+		{Name: AdminRole, Description: "Full access, including roles", Permissions: all},
+		{Name: "librarian", Description: "Manages the catalog, members, circulation and the trash", Permissions: []string{
+			PermBooksWrite, PermCategoriesWrite, PermUsersRead, PermUsersWrite, PermFinesWaive,
+			PermLoansBorrow, PermLoansManage, PermReservationsCreate, PermReservationsManage, PermTrashManage,
+		}},
+		{Name: "circulation_clerk", Description: "Lends and takes back books for members", Permissions: []string{
+			PermUsersRead, PermLoansBorrow, PermLoansManage, PermReservationsCreate, PermReservationsManage,
+		}},
+		{Name: "student", Description: "Borrows and reserves books", Permissions: []string{PermLoansBorrow, PermReservationsCreate}},
+		{Name: "faculty", Description: "Borrows and reserves books", Permissions: []string{PermLoansBorrow, PermReservationsCreate}},
+		{Name: "guest", Description: "Browses the catalog", Permissions: []string{}},
+	}
+}
@@ -9,14 +9,13 @@ import (
 type Transaction struct {
 	gorm.Model
 	UserID     uint       `json:"user_id"`
-	User       User       `gorm:"foreignKey:UserID"`
+	User       *User      `json:"user,omitempty" gorm:"foreignKey:UserID"` // only filled in when a response includes it
 	BookID     uint       `json:"book_id"`
-	Book       Book       `gorm:"foreignKey:BookID"`
+	Book       *Book      `json:"book,omitempty" gorm:"foreignKey:BookID"` // only filled in when a response includes it
+	CopyID     uint       `json:"copy_id"`                                 // the physical copy that was lent
 	BorrowDate time.Time  `json:"borrow_date"`
 	DueDate    time.Time  `json:"due_date"`
 	ReturnDate *time.Time `json:"return_date"` // Pointer to handle null values
+	LostAt     *time.Time `json:"lost_at"`     // set instead of ReturnDate when the borrower lost the copy
 	Fine       float64    `json:"fine"`
 }
-
-// You can add functions here later for getting transaction history if needed.
-// For now, the controller will handle creation and updates directly.
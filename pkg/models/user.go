@@ -1,29 +1,37 @@
 package models
 
 import (
+	"encoding/json"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// GetDB returns the database instance
-func GetDB() *gorm.DB {
-	return db
-}
-
 // User struct now includes the Password field for authentication
 type User struct {
 	gorm.Model
-	Name         string  `json:"name"`
-	Email        string  `json:"email" gorm:"unique"`
-	Password     string  `json:"password,omitempty"` // omitempty prevents it from being sent in responses if empty
-	MembershipID string  `json:"membership_id" gorm:"unique"`
-	Role         string  `json:"role"` // "staff" or "student"
-	Fines        float64 `json:"fines"`
+	Name         string  `json:"name" validate:"required"`
+	Email        string  `json:"email" gorm:"unique" validate:"required,email"`
+	Password     string  `json:"password,omitempty" validate:"required,min=8"` // write-only: see MarshalJSON
+	MembershipID string  `json:"membership_id" gorm:"unique" validate:"required"`
+	Role         string  `json:"role"` // name of a Role; DefaultRole when empty
+	Fines        float64 `json:"fines" validate:"min=0"`
+}
+
+// MarshalJSON leaves the password hash out of every JSON encoding of a user,
+// including users embedded in loans and reservations.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User // without the method, so Marshal does not recurse
+	plain := user(u)
+	plain.Password = ""
+	return json.Marshal(plain)
 }
 
 // BeforeSave is a GORM hook that automatically hashes the password before saving a user.
+// A password that is already a bcrypt hash, as on a user loaded for an
+// update, is kept as it is.
 func (u *User) BeforeSave(tx *gorm.DB) (err error) {
-	if u.Password != "" {
+	if _, costErr := bcrypt.Cost([]byte(u.Password)); u.Password != "" && costErr != nil {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return err
@@ -32,30 +40,3 @@ func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 	}
 	return
 }
-
-// CreateUser creates a new user in the database.
-func (u *User) CreateUser() (*User, error) {
-	result := db.Create(&u)
-	return u, result.Error
-}
-
-// GetAllUsers retrieves all users from the database.
-func GetAllUsers() []User {
-	var Users []User
-	db.Find(&Users)
-	return Users
-}
-
-// GetUserById retrieves a user by their ID.
-func GetUserById(Id int64) (*User, *gorm.DB) {
-	var getUser User
-	result := db.Where("id = ?", Id).First(&getUser)
-	return &getUser, result
-}
-
-// DeleteUser deletes a user by their ID.
-func DeleteUser(Id int64) User {
-	var user User
-	db.Where("id = ?", Id).Delete(&user)
-	return user
-}
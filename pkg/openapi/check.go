@@ -0,0 +1,428 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// routeVariable matches a mux path variable with a pattern, e.g. {id:[0-9]+}.
+var routeVariable = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+var pathParameter = regexp.MustCompile(`\{(\w+)\}`)
+
+// Body is what an operation's handler decodes from the request body and
+// encodes in a successful response. A nil type means there is no body.
+type Body struct {
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// Check compares the document with the routes registered on router, with the
+// Go types in schemas, keyed by component schema name, and with the bodies of
+// each route, keyed by "METHOD path" as the route was registered. The error
+// lists every difference: routes that are not documented or documented but
+// not routed, undeclared path parameters, unresolved $refs, component schemas
+// whose properties, types, required fields or enums differ from their Go
+// type, and request bodies or 2xx responses that do not describe the type the
+// handler reads or writes.
+func Check(router *mux.Router, schemas map[string]any, bodies map[string]Body) error {
+	var doc map[string]any
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+	c := &checker{doc: doc, types: map[reflect.Type]string{}}
+	for name, v := range schemas {
+		c.types[reflect.TypeOf(v)] = name
+	}
+
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		c.problem("openapi version is %q, want 3.x", v)
+	}
+	c.checkRefs("#", doc)
+	c.checkRoutes(router)
+	c.checkBodies(bodies)
+	c.checkSchemas(schemas)
+
+	if len(c.problems) == 0 {
+		return nil
+	}
+	sort.Strings(c.problems)
+	return errors.New("openapi: the document does not match the API:\n  " + strings.Join(c.problems, "\n  "))
+}
+
+type checker struct {
+	doc      map[string]any
+	types    map[reflect.Type]string // registered Go types by component name
+	problems []string
+}
+
+func (c *checker) problem(format string, args ...any) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// checkRefs reports every $ref below v that does not resolve.
+func (c *checker) checkRefs(at string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if _, ok := c.resolve(ref); !ok {
+				c.problem("%s: $ref %s does not resolve", at, ref)
+			}
+		}
+		for key, child := range v {
+			c.checkRefs(at+"/"+key, child)
+		}
+	case []any:
+		for i, child := range v {
+			c.checkRefs(fmt.Sprintf("%s/%d", at, i), child)
+		}
+	}
+}
+
+// resolve follows a local reference such as #/components/schemas/Book.
+func (c *checker) resolve(ref string) (map[string]any, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var node any = c.doc
+	for _, key := range strings.Split(ref[2:], "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+		if node, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	m, ok := node.(map[string]any)
+	return m, ok
+}
+
+// deref returns the object v refers to, or v itself when it is not a $ref.
+func (c *checker) deref(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	if ref, ok := m["$ref"].(string); ok {
+		m, _ = c.resolve(ref)
+	}
+	return m
+}
+
+func (c *checker) checkRoutes(router *mux.Router) {
+	routed := map[string]bool{}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Subrouter prefixes have no methods and are not endpoints.
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		path := routeVariable.ReplaceAllString(tpl, "{$1}")
+		for _, method := range methods {
+			routed[strings.ToLower(method)+" "+path] = true
+		}
+		return nil
+	})
+
+	documented := map[string]bool{}
+	paths, _ := c.doc["paths"].(map[string]any)
+	for path, v := range paths {
+		item, _ := v.(map[string]any)
+		for _, method := range operationMethods {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			documented[method+" "+path] = true
+			c.checkPathParameters(method, path, item, op)
+		}
+	}
+
+	for op := range routed {
+		if !documented[op] {
+			c.problem("route %s is not documented", operationName(op))
+		}
+	}
+	for op := range documented {
+		if !routed[op] {
+			c.problem("operation %s is documented but not routed", operationName(op))
+		}
+	}
+}
+
+// operationName turns "get /books" into "GET /books".
+func operationName(op string) string {
+	method, path, _ := strings.Cut(op, " ")
+	return strings.ToUpper(method) + " " + path
+}
+
+// checkPathParameters reports path variables without an "in: path" parameter.
+func (c *checker) checkPathParameters(method, path string, item, op map[string]any) {
+	declared := map[string]bool{}
+	for _, params := range []any{item["parameters"], op["parameters"]} {
+		list, _ := params.([]any)
+		for _, p := range list {
+			param := c.deref(p)
+			if param["in"] == "path" {
+				name, _ := param["name"].(string)
+				declared[name] = true
+			}
+		}
+	}
+	for _, m := range pathParameter.FindAllStringSubmatch(path, -1) {
+		if !declared[m[1]] {
+			c.problem("%s %s: path parameter %s is not declared", strings.ToUpper(method), path, m[1])
+		}
+	}
+}
+
+// checkBodies compares each operation's request body and 2xx responses with
+// the types its route reads and writes. Operations that are not routed are
+// reported by checkRoutes.
+func (c *checker) checkBodies(bodies map[string]Body) {
+	paths, _ := c.doc["paths"].(map[string]any)
+	for route, body := range bodies {
+		method, path, _ := strings.Cut(route, " ")
+		path = routeVariable.ReplaceAllString(path, "{$1}")
+		item, _ := paths[path].(map[string]any)
+		op, ok := item[strings.ToLower(method)].(map[string]any)
+		if !ok {
+			continue
+		}
+		name := method + " " + path
+
+		requestBody := c.deref(op["requestBody"])
+		switch {
+		case body.Request == nil && requestBody != nil:
+			c.problem("%s: documents a request body, but the handler reads none", name)
+		case body.Request != nil && requestBody == nil:
+			c.problem("%s: the handler reads %s, but no request body is documented", name, body.Request)
+		case body.Request != nil:
+			c.compareContent(name+" request", requestBody, body.Request)
+		}
+
+		responses, _ := op["responses"].(map[string]any)
+		for code, v := range responses {
+			if !strings.HasPrefix(code, "2") {
+				continue
+			}
+			response := c.deref(v)
+			_, documented := response["content"]
+			at := name + " response " + code
+			switch {
+			case body.Response == nil && documented:
+				c.problem("%s: documents a body, but the handler writes none", at)
+			case body.Response != nil && !documented:
+				c.problem("%s: the handler writes %s, but no body is documented", at, body.Response)
+			case body.Response != nil:
+				c.compareContent(at, response, body.Response)
+			}
+		}
+	}
+}
+
+// compareContent checks the schema of every media type of a request body or
+// response against t.
+func (c *checker) compareContent(at string, body map[string]any, t reflect.Type) {
+	content, _ := body["content"].(map[string]any)
+	for mediaType, v := range content {
+		media, _ := v.(map[string]any)
+		schema, _ := media["schema"].(map[string]any)
+		c.compare(at+" "+mediaType, schema, t)
+	}
+}
+
+func (c *checker) checkSchemas(schemas map[string]any) {
+	components, _ := c.doc["components"].(map[string]any)
+	specSchemas, _ := components["schemas"].(map[string]any)
+	for name := range specSchemas {
+		if _, ok := schemas[name]; !ok {
+			c.problem("schema %s has no Go type", name)
+		}
+	}
+	for name, v := range schemas {
+		schema, ok := specSchemas[name].(map[string]any)
+		if !ok {
+			c.problem("schema %s is not documented", name)
+			continue
+		}
+		c.compareObject(name, schema, reflect.TypeOf(v))
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// compare checks the schema of one value of Go type t.
+func (c *checker) compare(at string, schema map[string]any, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name, ok := c.types[t]; ok {
+		if want := "#/components/schemas/" + name; schema["$ref"] != want {
+			c.problem("%s: want $ref %s", at, want)
+		}
+		return
+	}
+	if ref, ok := schema["$ref"]; ok {
+		c.problem("%s: $ref %s, but %s is not a registered schema", at, ref, t)
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Interface:
+		// A handler that writes one of several types, e.g. the trash, which
+		// holds books, users or categories.
+		if _, ok := schema["oneOf"]; !ok {
+			c.problem("%s: %s may be one of several types, want oneOf", at, t)
+		}
+	case t == timeType || t == deletedAtType:
+		c.expectType(at, schema, "string")
+		if schema["format"] != "date-time" {
+			c.problem("%s: want format date-time", at)
+		}
+	case t.Kind() == reflect.Struct:
+		c.compareObject(at, schema, t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		c.expectType(at, schema, "array")
+		items, _ := schema["items"].(map[string]any)
+		c.compare(at+"[]", items, t.Elem())
+	case t.Kind() == reflect.Map:
+		c.expectType(at, schema, "object")
+	case t.Kind() == reflect.String:
+		c.expectType(at, schema, "string")
+	case t.Kind() == reflect.Bool:
+		c.expectType(at, schema, "boolean")
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		c.expectType(at, schema, "integer")
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		c.expectType(at, schema, "number")
+	}
+}
+
+func (c *checker) expectType(at string, schema map[string]any, want string) {
+	if got := schema["type"]; got != want {
+		c.problem("%s: type is %v, want %s", at, got, want)
+	}
+}
+
+// compareObject checks the properties, required fields and enums of a struct.
+func (c *checker) compareObject(at string, schema map[string]any, t reflect.Type) {
+	if _, ok := schema["allOf"]; !ok {
+		c.expectType(at, schema, "object")
+	}
+	props, required := c.properties(schema)
+
+	var wantRequired []string
+	fields := jsonFields(t)
+	for _, f := range fields {
+		prop, ok := props[f.name]
+		if !ok {
+			c.problem("%s: property %s is missing", at, f.name)
+			continue
+		}
+		c.compare(at+"."+f.name, prop, f.typ)
+
+		for _, rule := range strings.Split(f.validate, ",") {
+			rule, arg, _ := strings.Cut(rule, "=")
+			switch rule {
+			case "required":
+				wantRequired = append(wantRequired, f.name)
+			case "oneof":
+				var enum []string
+				list, _ := prop["enum"].([]any)
+				for _, v := range list {
+					enum = append(enum, fmt.Sprint(v))
+				}
+				if !slices.Equal(enum, strings.Fields(arg)) {
+					c.problem("%s.%s: enum is %v, want %v", at, f.name, enum, strings.Fields(arg))
+				}
+			}
+		}
+	}
+	for name := range props {
+		if !slices.ContainsFunc(fields, func(f jsonField) bool { return f.name == name }) {
+			c.problem("%s: property %s does not exist in %s", at, name, t)
+		}
+	}
+
+	slices.Sort(required)
+	slices.Sort(wantRequired)
+	if !slices.Equal(required, wantRequired) {
+		c.problem("%s: required is %v, want %v", at, required, wantRequired)
+	}
+}
+
+// properties collects an object schema's properties and required fields,
+// including those of its allOf members.
+func (c *checker) properties(schema map[string]any) (map[string]map[string]any, []string) {
+	props := map[string]map[string]any{}
+	var required []string
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, member := range all {
+			p, r := c.properties(c.deref(member))
+			for name, prop := range p {
+				props[name] = prop
+			}
+			required = append(required, r...)
+		}
+	}
+	own, _ := schema["properties"].(map[string]any)
+	for name, v := range own {
+		prop, _ := v.(map[string]any)
+		props[name] = prop
+	}
+	list, _ := schema["required"].([]any)
+	for _, v := range list {
+		required = append(required, fmt.Sprint(v))
+	}
+	return props, required
+}
+
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	validate string
+}
+
+// jsonFields lists the fields encoding/json writes for struct type t, with
+// embedded structs flattened into their parent.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type, validate: f.Tag.Get("validate")})
+	}
+	return fields
+}
@@ -0,0 +1,89 @@
+// Package openapi serves the OpenAPI 3 description of the API and a Swagger
+// UI page for browsing it. The document in openapi.json is written by hand;
+// Check keeps it honest by comparing it with the registered routes and the
+// Go types behind each schema.
+package openapi
+
+import (
+	"embed"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/gorilla/mux"
+)
+
+//go:embed openapi.json
+var document []byte
+
+//go:generate go run ../../cmd/openapi fetch-ui swaggerui
+
+// SwaggerUIVersion is the swagger-ui-dist release the docs page uses.
+const SwaggerUIVersion = "5.18.2"
+
+// SwaggerUIFiles are the files of swagger-ui-dist the docs page loads.
+var SwaggerUIFiles = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+// swaggerUI holds SwaggerUIFiles, which are committed and refreshed by
+// "go generate". The page loads them from ServeUIFile, never from a CDN.
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// uiPage loads SwaggerUIFiles and the document relative to its own URL, so
+// it works under any version prefix.
+var uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>BookHive API</title>
+  <link rel="stylesheet" href="docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// Document returns the OpenAPI document as JSON.
+func Document() []byte {
+	return document
+}
+
+// ServeDocument writes the OpenAPI document.
+func ServeDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+// ServeUI writes a Swagger UI page that loads the document from openapi.json
+// next to it. The Swagger UI scripts and styles come from ServeUIFile.
+func ServeUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(uiPage))
+}
+
+// ServeUIFile writes the Swagger UI file named by the file route variable,
+// one of SwaggerUIFiles.
+func ServeUIFile(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["file"]
+	data, err := swaggerUI.ReadFile("swaggerui/" + name)
+	if !slices.Contains(SwaggerUIFiles, name) || err != nil {
+		apierror.Write(w, apierror.NotFound("No such file"))
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
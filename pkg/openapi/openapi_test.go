@@ -0,0 +1,131 @@
+package openapi_test
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+	"github.com/J-Mihir/go-bookstore/pkg/routes"
+	"github.com/gorilla/mux"
+)
+
+// v1Router registers the v1 routes the document describes. Only the routes
+// are inspected, so the handlers need no dependencies.
+func v1Router(t *testing.T) *mux.Router {
+	t.Helper()
+	router := mux.NewRouter()
+	if err := routes.V1.Register(router, &controllers.Handler{}, &middleware.Auth{}); err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestDocumentMatchesAPI(t *testing.T) {
+	if err := openapi.Check(v1Router(t), controllers.Schemas, routes.V1.Bodies()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRejectsBodyDrift(t *testing.T) {
+	tests := []struct {
+		route string
+		body  openapi.Body
+		want  string
+	}{
+		{
+			"POST /transactions/borrow",
+			routes.Types[controllers.ReservationRequest, models.Transaction](),
+			"POST /transactions/borrow request application/json: want $ref #/components/schemas/ReservationRequest",
+		},
+		{
+			"GET /books",
+			routes.Types[routes.None, []models.User](),
+			"GET /books response 200 application/json[]: want $ref #/components/schemas/User",
+		},
+		{
+			"POST /login",
+			routes.Types[routes.None, controllers.TokenResponse](),
+			"POST /login: documents a request body, but the handler reads none",
+		},
+		{
+			"DELETE /roles/{roleId}",
+			routes.Types[routes.None, models.Role](),
+			"DELETE /roles/{roleId} response 204: the handler writes models.Role, but no body is documented",
+		},
+		{
+			"POST /trash/{resource:books|users|categories}/{id}/restore",
+			routes.Types[routes.None, models.Book](),
+			"POST /trash/{resource}/{id}/restore response 200 application/json: want $ref #/components/schemas/Book",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			bodies := routes.V1.Bodies()
+			if _, ok := bodies[tt.route]; !ok {
+				t.Fatalf("v1 has no route %s", tt.route)
+			}
+			bodies[tt.route] = tt.body
+			err := openapi.Check(v1Router(t), controllers.Schemas, bodies)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Check() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUIFilesAreLimitedToSwaggerUI(t *testing.T) {
+	router := v1Router(t)
+	for path, want := range map[string]int{
+		"/docs":                       http.StatusOK,
+		"/docs/openapi.go":            http.StatusNotFound,
+		"/docs/swaggerui%2FREADME.md": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestUIIsServedFromEmbeddedFiles(t *testing.T) {
+	router := mux.NewRouter()
+	if err := routes.Mount(router, &controllers.Handler{}, &middleware.Auth{}, routes.V1); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	if page := rec.Body.String(); strings.Contains(page, "://") {
+		t.Errorf("GET /api/v1/docs loads files from another site:\n%s", page)
+	}
+
+	for _, name := range openapi.SwaggerUIFiles {
+		want, err := os.ReadFile(filepath.Join("swaggerui", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := "/api/v1/docs/" + name
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+			continue
+		}
+		if !bytes.Equal(rec.Body.Bytes(), want) {
+			t.Errorf("GET %s does not return swaggerui/%s", path, name)
+		}
+		if ct, want := rec.Header().Get("Content-Type"), mime.TypeByExtension(filepath.Ext(name)); ct != want {
+			t.Errorf("GET %s has Content-Type %q, want %q", path, ct, want)
+		}
+	}
+}
@@ -0,0 +1,673 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormStore implements Store on top of a GORM connection.
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a Store backed by the given database.
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Books() BookRepository                   { return gormBooks{s.db} }
+func (s *gormStore) Copies() CopyRepository                  { return gormCopies{s.db} }
+func (s *gormStore) Users() UserRepository                   { return gormUsers{s.db} }
+func (s *gormStore) Categories() CategoryRepository          { return gormCategories{s.db} }
+func (s *gormStore) Transactions() TransactionRepository     { return gormTransactions{s.db} }
+func (s *gormStore) Reservations() ReservationRepository     { return gormReservations{s.db} }
+func (s *gormStore) RefreshTokens() RefreshTokenRepository   { return gormRefreshTokens{s.db} }
+func (s *gormStore) PasswordResets() PasswordResetRepository { return gormPasswordResets{s.db} }
+func (s *gormStore) Roles() RoleRepository                   { return gormRoles{s.db} }
+
+func (s *gormStore) Atomic(fn func(Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// forUpdate adds a SELECT ... FOR UPDATE row lock. Dialects without row locks
+// (SQLite) drop the clause and rely on the transaction's database lock instead.
+func forUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// translate maps GORM errors onto the repository's sentinel errors.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrInvalidReference
+	default:
+		return err
+	}
+}
+
+// listDeleted returns the soft-deleted rows of T, most recently deleted first.
+func listDeleted[T any](db *gorm.DB) ([]T, error) {
+	var rows []T
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&rows).Error
+	return rows, translate(err)
+}
+
+// restore clears deleted_at on a soft-deleted row of T and returns the row.
+func restore[T any](db *gorm.DB, id uint) (*T, error) {
+	result := db.Unscoped().Model(new(T)).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		return nil, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	var row T
+	if err := db.First(&row, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &row, nil
+}
+
+// purgeDeletedBefore permanently deletes rows of T soft-deleted before cutoff.
+func purgeDeletedBefore[T any](db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(new(T))
+	return result.RowsAffected, translate(result.Error)
+}
+
+type gormBooks struct{ db *gorm.DB }
+
+func (r gormBooks) ListDeleted() ([]models.Book, error) {
+	books, err := listDeleted[models.Book](r.db)
+	if err != nil {
+		return nil, err
+	}
+	return books, fillInventory(r.db, pointers(books)...)
+}
+
+func (r gormBooks) Restore(id uint) (*models.Book, error) {
+	book, err := restore[models.Book](r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return book, fillInventory(r.db, book)
+}
+
+// PurgeDeletedBefore also removes the copies of the purged books. Loan and
+// reservation history keeps its book IDs. Books with copies on loan or on
+// hold stay in the trash, since open loans and held reservations refer to
+// those copies.
+func (r gormBooks) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		circulating := tx.Model(&models.BookCopy{}).Select("book_id").
+			Where("status IN ?", []models.CopyStatus{models.CopyBorrowed, models.CopyReserved})
+		var ids []uint
+		err := tx.Unscoped().Model(&models.Book{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND id NOT IN (?)", cutoff, circulating).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.Unscoped().Where("book_id IN ?", ids).Delete(&models.BookCopy{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Book{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, translate(err)
+}
+
+func (r gormBooks) Create(book *models.Book) error {
+	return translate(r.db.Create(book).Error)
+}
+
+func (r gormBooks) List(q ListQuery) ([]models.Book, int64, error) {
+	books, total, err := gormList(r.db, bookListFields, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	return books, total, fillInventory(r.db, pointers(books)...)
+}
+
+func (r gormBooks) FindByID(id uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.First(&book, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &book, fillInventory(r.db, &book)
+}
+
+func (r gormBooks) FindByIDs(ids []uint) ([]models.Book, error) {
+	if len(ids) == 0 {
+		return []models.Book{}, nil
+	}
+	var found []models.Book
+	if err := r.db.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, translate(err)
+	}
+	byID := make(map[uint]models.Book, len(found))
+	for _, b := range found {
+		byID[b.ID] = b
+	}
+	books := make([]models.Book, 0, len(found))
+	for _, id := range ids {
+		if b, ok := byID[id]; ok {
+			books = append(books, b)
+		}
+	}
+	return books, fillInventory(r.db, pointers(books)...)
+}
+
+func (r gormBooks) FindForUpdate(id uint) (*models.Book, error) {
+	var book models.Book
+	if err := forUpdate(r.db).First(&book, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &book, fillInventory(r.db, &book)
+}
+
+func (r gormBooks) Update(book *models.Book) error {
+	return translate(r.db.Save(book).Error)
+}
+
+func (r gormBooks) Delete(id uint) (*models.Book, error) {
+	book, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return book, translate(r.db.Delete(book).Error)
+}
+
+func (r gormBooks) CountByCategory(categoryID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Book{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, translate(err)
+}
+
+// fillInventory sets the computed inventory fields of each book
+// with a single grouped query over book_copies.
+func fillInventory(db *gorm.DB, books ...*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+	}
+
+	var rows []struct {
+		BookID uint
+		Status models.CopyStatus
+		Count  int
+	}
+	err := db.Model(&models.BookCopy{}).
+		Select("book_id, status, COUNT(*) AS count").
+		Where("book_id IN ?", ids).
+		Group("book_id, status").
+		Scan(&rows).Error
+	if err != nil {
+		return translate(err)
+	}
+
+	counts := make(map[uint]map[models.CopyStatus]int, len(books))
+	for _, row := range rows {
+		if counts[row.BookID] == nil {
+			counts[row.BookID] = map[models.CopyStatus]int{}
+		}
+		counts[row.BookID][row.Status] = row.Count
+	}
+	for _, b := range books {
+		b.SetInventory(counts[b.ID])
+	}
+	return nil
+}
+
+func pointers[T any](rows []T) []*T {
+	ptrs := make([]*T, len(rows))
+	for i := range rows {
+		ptrs[i] = &rows[i]
+	}
+	return ptrs
+}
+
+type gormCopies struct{ db *gorm.DB }
+
+func (r gormCopies) Create(bookCopy *models.BookCopy) error {
+	return translate(r.db.Create(bookCopy).Error)
+}
+
+func (r gormCopies) FindByID(id uint) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	if err := r.db.First(&bookCopy, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &bookCopy, nil
+}
+
+func (r gormCopies) FindByBarcode(barcode string) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	if err := r.db.Where("barcode = ?", barcode).First(&bookCopy).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &bookCopy, nil
+}
+
+func (r gormCopies) FindForUpdate(id uint) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	if err := forUpdate(r.db).First(&bookCopy, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &bookCopy, nil
+}
+
+func (r gormCopies) ListByBook(bookID uint) ([]models.BookCopy, error) {
+	var copies []models.BookCopy
+	err := r.db.Where("book_id = ?", bookID).Order("id").Find(&copies).Error
+	return copies, translate(err)
+}
+
+func (r gormCopies) CountAllByBook(bookID uint) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.BookCopy{}).Where("book_id = ?", bookID).Count(&count).Error
+	return count, translate(err)
+}
+
+func (r gormCopies) FirstAvailable(bookID uint) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	err := forUpdate(r.db).Where("book_id = ? AND status = ?", bookID, models.CopyAvailable).Order("id").First(&bookCopy).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &bookCopy, nil
+}
+
+func (r gormCopies) Update(bookCopy *models.BookCopy) error {
+	return translate(r.db.Save(bookCopy).Error)
+}
+
+func (r gormCopies) Delete(id uint) error {
+	result := r.db.Delete(&models.BookCopy{}, id)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormUsers struct{ db *gorm.DB }
+
+func (r gormUsers) ListDeleted() ([]models.User, error) {
+	return listDeleted[models.User](r.db)
+}
+
+func (r gormUsers) Restore(id uint) (*models.User, error) {
+	return restore[models.User](r.db, id)
+}
+
+func (r gormUsers) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore[models.User](r.db, cutoff)
+}
+
+func (r gormUsers) Create(user *models.User) error {
+	return translate(r.db.Create(user).Error)
+}
+
+func (r gormUsers) List(q ListQuery) ([]models.User, int64, error) {
+	return gormList(r.db, userListFields, q)
+}
+
+func (r gormUsers) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r gormUsers) FindForUpdate(id uint) (*models.User, error) {
+	var user models.User
+	if err := forUpdate(r.db).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r gormUsers) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r gormUsers) Update(user *models.User) error {
+	return translate(r.db.Save(user).Error)
+}
+
+func (r gormUsers) Delete(id uint) (*models.User, error) {
+	user, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return user, translate(r.db.Delete(user).Error)
+}
+
+type gormCategories struct{ db *gorm.DB }
+
+func (r gormCategories) ListDeleted() ([]models.Category, error) {
+	return listDeleted[models.Category](r.db)
+}
+
+func (r gormCategories) Restore(id uint) (*models.Category, error) {
+	return restore[models.Category](r.db, id)
+}
+
+func (r gormCategories) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	return purgeDeletedBefore[models.Category](r.db, cutoff)
+}
+
+func (r gormCategories) Create(category *models.Category) error {
+	return translate(r.db.Create(category).Error)
+}
+
+func (r gormCategories) List(q ListQuery) ([]models.Category, int64, error) {
+	return gormList(r.db, categoryListFields, q)
+}
+
+func (r gormCategories) FindByID(id uint) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.First(&category, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &category, nil
+}
+
+func (r gormCategories) FindForUpdate(id uint) (*models.Category, error) {
+	var category models.Category
+	if err := forUpdate(r.db).First(&category, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &category, nil
+}
+
+func (r gormCategories) Update(category *models.Category) error {
+	return translate(r.db.Save(category).Error)
+}
+
+func (r gormCategories) Delete(id uint) error {
+	result := r.db.Delete(&models.Category{}, id)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormTransactions struct{ db *gorm.DB }
+
+func (r gormTransactions) Create(transaction *models.Transaction) error {
+	return translate(r.db.Omit("User", "Book").Create(transaction).Error)
+}
+
+func (r gormTransactions) FindByID(id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := r.db.First(&transaction, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &transaction, nil
+}
+
+func (r gormTransactions) FindForUpdate(id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := forUpdate(r.db).First(&transaction, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &transaction, nil
+}
+
+func (r gormTransactions) Update(transaction *models.Transaction) error {
+	return translate(r.db.Omit("User", "Book").Save(transaction).Error)
+}
+
+func (r gormTransactions) CountActiveByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Transaction{}).Where("user_id = ? AND return_date IS NULL AND lost_at IS NULL", userID).Count(&count).Error
+	return count, translate(err)
+}
+
+type gormReservations struct{ db *gorm.DB }
+
+func (r gormReservations) Create(reservation *models.Reservation) error {
+	return translate(r.db.Omit("User", "Book").Create(reservation).Error)
+}
+
+func (r gormReservations) FindPending(userID, bookID uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.Where("user_id = ? AND book_id = ? AND status = ?", userID, bookID, "Pending").First(&reservation).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &reservation, nil
+}
+
+func (r gormReservations) FindHeld(userID, bookID uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.Where("user_id = ? AND book_id = ? AND status = ? AND copy_id IS NOT NULL", userID, bookID, "Fulfilled").First(&reservation).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &reservation, nil
+}
+
+func (r gormReservations) OldestPendingForBook(bookID uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.Where("book_id = ? AND status = ?", bookID, "Pending").Order("created_at asc").First(&reservation).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &reservation, nil
+}
+
+func (r gormReservations) Update(reservation *models.Reservation) error {
+	return translate(r.db.Omit("User", "Book").Save(reservation).Error)
+}
+
+type gormRefreshTokens struct{ db *gorm.DB }
+
+func (r gormRefreshTokens) Create(token *models.RefreshToken) error {
+	return translate(r.db.Create(token).Error)
+}
+
+func (r gormRefreshTokens) FindByHashForUpdate(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := forUpdate(r.db).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r gormRefreshTokens) Update(token *models.RefreshToken) error {
+	return translate(r.db.Save(token).Error)
+}
+
+func (r gormRefreshTokens) SessionActive(sessionID string, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, now).
+		Count(&count).Error
+	return count > 0, translate(err)
+}
+
+func (r gormRefreshTokens) RevokeSession(sessionID string, at time.Time) error {
+	return translate(r.db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", at).Error)
+}
+
+func (r gormRefreshTokens) RevokeUser(userID uint, at time.Time) error {
+	return translate(r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error)
+}
+
+func (r gormRefreshTokens) RevokeOtherSessions(userID uint, keep string, at time.Time) error {
+	return translate(r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND session_id <> ? AND revoked_at IS NULL", userID, keep).
+		Update("revoked_at", at).Error)
+}
+
+func (r gormRefreshTokens) PurgeExpiredBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("expires_at < ?", cutoff).Delete(&models.RefreshToken{})
+	return result.RowsAffected, translate(result.Error)
+}
+
+type gormPasswordResets struct{ db *gorm.DB }
+
+func (r gormPasswordResets) Create(reset *models.PasswordReset) error {
+	return translate(r.db.Create(reset).Error)
+}
+
+func (r gormPasswordResets) FindByHashForUpdate(hash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := forUpdate(r.db).Where("token_hash = ?", hash).First(&reset).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &reset, nil
+}
+
+func (r gormPasswordResets) UseAll(userID uint, at time.Time) error {
+	return translate(r.db.Model(&models.PasswordReset{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error)
+}
+
+func (r gormPasswordResets) CountIssuedSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.PasswordReset{}).Where("user_id = ? AND created_at >= ?", userID, since).Count(&count).Error
+	return count, translate(err)
+}
+
+func (r gormPasswordResets) PurgeExpiredBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("expires_at < ?", cutoff).Delete(&models.PasswordReset{})
+	return result.RowsAffected, translate(result.Error)
+}
+
+type gormRoles struct{ db *gorm.DB }
+
+func (r gormRoles) Create(role *models.Role) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		return gormRoles{tx}.savePermissions(role)
+	}))
+}
+
+func (r gormRoles) List() ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, r.loadPermissions(roles)
+}
+
+func (r gormRoles) FindByID(id uint) (*models.Role, error) {
+	return r.find(r.db.Where("id = ?", id))
+}
+
+func (r gormRoles) FindByName(name string) (*models.Role, error) {
+	return r.find(r.db.Where("name = ?", name))
+}
+
+func (r gormRoles) FindForUpdate(id uint) (*models.Role, error) {
+	return r.find(forUpdate(r.db).Where("id = ?", id))
+}
+
+func (r gormRoles) find(query *gorm.DB) (*models.Role, error) {
+	var role models.Role
+	if err := query.First(&role).Error; err != nil {
+		return nil, translate(err)
+	}
+	roles := []models.Role{role}
+	if err := r.loadPermissions(roles); err != nil {
+		return nil, err
+	}
+	return &roles[0], nil
+}
+
+// loadPermissions fills in the permissions of each role with one query.
+func (r gormRoles) loadPermissions(roles []models.Role) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	index := make(map[uint]int, len(roles))
+	ids := make([]uint, len(roles))
+	for i := range roles {
+		roles[i].Permissions = []string{}
+		index[roles[i].ID] = i
+		ids[i] = roles[i].ID
+	}
+	var grants []models.RolePermission
+	if err := r.db.Where("role_id IN ?", ids).Order("permission").Find(&grants).Error; err != nil {
+		return err
+	}
+	for _, g := range grants {
+		i := index[g.RoleID]
+		roles[i].Permissions = append(roles[i].Permissions, g.Permission)
+	}
+	return nil
+}
+
+func (r gormRoles) Update(role *models.Role) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Updates(map[string]interface{}{"description": role.Description}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return gormRoles{tx}.savePermissions(role)
+	}))
+}
+
+func (r gormRoles) savePermissions(role *models.Role) error {
+	for _, p := range role.Permissions {
+		if err := r.db.Create(&models.RolePermission{RoleID: role.ID, Permission: p}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r gormRoles) Delete(id uint) error {
+	return translate(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.Role{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}))
+}
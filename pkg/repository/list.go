@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidQuery is returned when a list query filters or sorts on an
+// unsupported field or uses a malformed filter value.
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// ListQuery selects one page of records for a list endpoint.
+type ListQuery struct {
+	// Filters maps a field name to the value it must match.
+	Filters map[string]string
+	// Sort orders the results; ties are always broken by ascending ID.
+	Sort []SortField
+	// Offset is the number of matching records to skip.
+	Offset int
+	// Limit caps the number of records returned; zero returns them all.
+	Limit int
+}
+
+// SortField orders the results by one field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma-separated list of field names, each optionally
+// prefixed with "-" for descending order, e.g. "-created_at,name".
+func ParseSort(s string) []SortField {
+	var fields []SortField
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name != "" {
+			fields = append(fields, SortField{Field: name, Desc: desc})
+		}
+	}
+	return fields
+}
+
+// matchKind is how a filter value is compared with a field.
+type matchKind int
+
+const (
+	matchNone     matchKind = iota // the field cannot be filtered on
+	matchExact                     // equal to the value
+	matchContains                  // contains the value, ignoring case
+	matchID                        // equal to the value parsed as an ID
+)
+
+// listField describes a field that list queries may filter or sort on.
+// Both store implementations read the same definitions, so they accept
+// exactly the same queries.
+type listField[T any] struct {
+	column   string
+	value    func(*T) any
+	sortable bool
+	match    matchKind
+	// values, if set, restricts the accepted filter values.
+	values []string
+	// where, if set, replaces the column comparison in SQL.
+	where func(db *gorm.DB, value string) *gorm.DB
+}
+
+var bookListFields = map[string]listField[models.Book]{
+	"id":          {column: "id", value: func(b *models.Book) any { return b.ID }, sortable: true, match: matchID},
+	"name":        {column: "name", value: func(b *models.Book) any { return b.Name }, sortable: true, match: matchContains},
+	"author":      {column: "author", value: func(b *models.Book) any { return b.Author }, sortable: true, match: matchContains},
+	"publication": {column: "publication", value: func(b *models.Book) any { return b.Publication }, sortable: true, match: matchContains},
+	"genre":       {column: "genre", value: func(b *models.Book) any { return b.Genre }, sortable: true, match: matchContains},
+	"isbn":        {column: "isbn", value: func(b *models.Book) any { return b.ISBN }, sortable: true, match: matchExact},
+	"edition":     {column: "edition", value: func(b *models.Book) any { return b.Edition }, sortable: true, match: matchExact},
+	"category_id": {column: "category_id", value: func(b *models.Book) any { return b.CategoryID }, sortable: true, match: matchID},
+	"created_at":  {column: "created_at", value: func(b *models.Book) any { return b.CreatedAt }, sortable: true},
+	"updated_at":  {column: "updated_at", value: func(b *models.Book) any { return b.UpdatedAt }, sortable: true},
+	"availability": {
+		value:  func(b *models.Book) any { return string(b.Availability) },
+		match:  matchExact,
+		values: []string{string(models.Available), string(models.OnHold), string(models.OnLoan), string(models.NotAvailable)},
+		where:  availabilityWhere,
+	},
+}
+
+var userListFields = map[string]listField[models.User]{
+	"id":            {column: "id", value: func(u *models.User) any { return u.ID }, sortable: true, match: matchID},
+	"name":          {column: "name", value: func(u *models.User) any { return u.Name }, sortable: true, match: matchContains},
+	"email":         {column: "email", value: func(u *models.User) any { return u.Email }, sortable: true, match: matchContains},
+	"membership_id": {column: "membership_id", value: func(u *models.User) any { return u.MembershipID }, sortable: true, match: matchExact},
+	"role":          {column: "role", value: func(u *models.User) any { return u.Role }, sortable: true, match: matchExact},
+	"fines":         {column: "fines", value: func(u *models.User) any { return u.Fines }, sortable: true},
+	"created_at":    {column: "created_at", value: func(u *models.User) any { return u.CreatedAt }, sortable: true},
+	"updated_at":    {column: "updated_at", value: func(u *models.User) any { return u.UpdatedAt }, sortable: true},
+}
+
+var categoryListFields = map[string]listField[models.Category]{
+	"id":         {column: "id", value: func(c *models.Category) any { return c.ID }, sortable: true, match: matchID},
+	"name":       {column: "name", value: func(c *models.Category) any { return c.Name }, sortable: true, match: matchContains},
+	"created_at": {column: "created_at", value: func(c *models.Category) any { return c.CreatedAt }, sortable: true},
+	"updated_at": {column: "updated_at", value: func(c *models.Category) any { return c.UpdatedAt }, sortable: true},
+}
+
+// validateListQuery reports the first filter or sort field q may not use.
+func validateListQuery[T any](fields map[string]listField[T], q ListQuery) error {
+	for name, value := range q.Filters {
+		f, ok := fields[name]
+		if !ok || f.match == matchNone {
+			return fmt.Errorf("%w: cannot filter by %q", ErrInvalidQuery, name)
+		}
+		if f.match == matchID {
+			if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+				return fmt.Errorf("%w: %s must be a positive integer", ErrInvalidQuery, name)
+			}
+		}
+		if len(f.values) > 0 && !slices.Contains(f.values, value) {
+			return fmt.Errorf("%w: %s must be one of %s", ErrInvalidQuery, name, strings.Join(f.values, ", "))
+		}
+	}
+	for _, s := range q.Sort {
+		if f, ok := fields[s.Field]; !ok || !f.sortable {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, s.Field)
+		}
+	}
+	return nil
+}
+
+// gormList runs q against the table of T and returns the page together with
+// the number of records matching the filters.
+func gormList[T any](db *gorm.DB, fields map[string]listField[T], q ListQuery) ([]T, int64, error) {
+	if err := validateListQuery(fields, q); err != nil {
+		return nil, 0, err
+	}
+
+	query := db.Model(new(T))
+	for name, value := range q.Filters {
+		f := fields[name]
+		switch {
+		case f.where != nil:
+			query = f.where(query, value)
+		case f.match == matchContains:
+			query = query.Where("LOWER("+f.column+") LIKE ? ESCAPE '!'", "%"+EscapeLike(strings.ToLower(value))+"%")
+		case f.match == matchID:
+			id, _ := strconv.ParseUint(value, 10, 64)
+			query = query.Where(clause.Eq{Column: clause.Column{Name: f.column}, Value: id})
+		default:
+			query = query.Where(clause.Eq{Column: clause.Column{Name: f.column}, Value: value})
+		}
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, translate(err)
+	}
+
+	var order []clause.OrderByColumn
+	for _, s := range q.Sort {
+		order = append(order, clause.OrderByColumn{Column: clause.Column{Name: fields[s.Field].column}, Desc: s.Desc})
+	}
+	order = append(order, clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+	page := query.Order(clause.OrderBy{Columns: order}).Offset(q.Offset)
+	if q.Limit > 0 {
+		page = page.Limit(q.Limit)
+	}
+
+	rows := []T{}
+	if err := page.Find(&rows).Error; err != nil {
+		return nil, 0, translate(err)
+	}
+	return rows, total, nil
+}
+
+// EscapeLike escapes the LIKE wildcards in s using '!' as the escape
+// character, which every supported dialect accepts.
+func EscapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// hasCopyIn matches books with at least one live copy in the given status.
+const hasCopyIn = "EXISTS (SELECT 1 FROM book_copies WHERE book_copies.book_id = books.id AND book_copies.deleted_at IS NULL AND book_copies.status = ?)"
+
+// availabilityWhere filters books on their computed availability, mirroring
+// the precedence in models.Book.SetInventory.
+func availabilityWhere(db *gorm.DB, value string) *gorm.DB {
+	precedence := []models.CopyStatus{models.CopyAvailable, models.CopyReserved, models.CopyBorrowed}
+	var status models.CopyStatus
+	switch models.Availability(value) {
+	case models.Available:
+		status = models.CopyAvailable
+	case models.OnHold:
+		status = models.CopyReserved
+	case models.OnLoan:
+		status = models.CopyBorrowed
+	}
+	for _, s := range precedence {
+		if s == status {
+			return db.Where(hasCopyIn, s)
+		}
+		db = db.Where("NOT "+hasCopyIn, s)
+	}
+	return db
+}
+
+// memoryList applies q to rows, which must be ordered by ID, and returns the
+// page together with the number of rows matching the filters.
+func memoryList[T any](rows []T, fields map[string]listField[T], q ListQuery) ([]T, int64, error) {
+	if err := validateListQuery(fields, q); err != nil {
+		return nil, 0, err
+	}
+
+	matched := rows[:0]
+	for i := range rows {
+		if memoryMatches(&rows[i], fields, q.Filters) {
+			matched = append(matched, rows[i])
+		}
+	}
+
+	// The stable sort keeps the ID order between equal values.
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, s := range q.Sort {
+			value := fields[s.Field].value
+			c := compareValues(value(&matched[i]), value(&matched[j]))
+			if c != 0 {
+				return (c < 0) != s.Desc
+			}
+		}
+		return false
+	})
+
+	total := int64(len(matched))
+	start := min(q.Offset, len(matched))
+	end := len(matched)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, end)
+	}
+	return append([]T{}, matched[start:end]...), total, nil
+}
+
+func memoryMatches[T any](row *T, fields map[string]listField[T], filters map[string]string) bool {
+	for name, want := range filters {
+		f := fields[name]
+		got := fmt.Sprint(f.value(row))
+		switch f.match {
+		case matchContains:
+			if !strings.Contains(strings.ToLower(got), strings.ToLower(want)) {
+				return false
+			}
+		case matchID:
+			id, _ := strconv.ParseUint(want, 10, 64)
+			if got != strconv.FormatUint(id, 10) {
+				return false
+			}
+		default:
+			if got != want {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compareValues orders two field values of the same type.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case uint:
+		return cmp.Compare(a, b.(uint))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
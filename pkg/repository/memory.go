@@ -0,0 +1,896 @@
+package repository
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"gorm.io/gorm"
+)
+
+// memoryStore implements Store in process memory. It mirrors the GORM behaviour
+// the controllers rely on (auto IDs, timestamps, soft deletes, unique fields)
+// so handlers and business rules can be exercised without a database.
+type memoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	// inTx is set on the view handed to an Atomic callback, which already holds mu.
+	inTx bool
+}
+
+type memoryData struct {
+	books        table[models.Book]
+	copies       table[models.BookCopy]
+	users        table[models.User]
+	categories   table[models.Category]
+	transactions table[models.Transaction]
+	reservations table[models.Reservation]
+	tokens       table[models.RefreshToken]
+	resets       table[models.PasswordReset]
+	roles        table[models.Role]
+}
+
+// NewMemoryStore returns an in-memory Store that is empty apart from the
+// default roles, which a database gets from its migrations.
+func NewMemoryStore() Store {
+	s := &memoryStore{mu: &sync.Mutex{}, data: &memoryData{
+		books:        newTable(func(b *models.Book) *gorm.Model { return &b.Model }),
+		copies:       newTable(func(c *models.BookCopy) *gorm.Model { return &c.Model }),
+		users:        newTable(func(u *models.User) *gorm.Model { return &u.Model }),
+		categories:   newTable(func(c *models.Category) *gorm.Model { return &c.Model }),
+		transactions: newTable(func(t *models.Transaction) *gorm.Model { return &t.Model }),
+		reservations: newTable(func(r *models.Reservation) *gorm.Model { return &r.Model }),
+		tokens:       newTable(func(t *models.RefreshToken) *gorm.Model { return &t.Model }),
+		resets:       newTable(func(r *models.PasswordReset) *gorm.Model { return &r.Model }),
+		roles:        newTable(func(r *models.Role) *gorm.Model { return &r.Model }),
+	}}
+	for _, role := range models.DefaultRoles() {
+		s.data.roles.insert(&role)
+	}
+	return s
+}
+
+func (s *memoryStore) Books() BookRepository                   { return memoryBooks{s} }
+func (s *memoryStore) Copies() CopyRepository                  { return memoryCopies{s} }
+func (s *memoryStore) Users() UserRepository                   { return memoryUsers{s} }
+func (s *memoryStore) Categories() CategoryRepository          { return memoryCategories{s} }
+func (s *memoryStore) Transactions() TransactionRepository     { return memoryTransactions{s} }
+func (s *memoryStore) Reservations() ReservationRepository     { return memoryReservations{s} }
+func (s *memoryStore) RefreshTokens() RefreshTokenRepository   { return memoryRefreshTokens{s} }
+func (s *memoryStore) PasswordResets() PasswordResetRepository { return memoryPasswordResets{s} }
+func (s *memoryStore) Roles() RoleRepository                   { return memoryRoles{s} }
+
+// Atomic runs fn while holding the store lock, which serialises it against every
+// other operation, and restores the previous contents if fn fails.
+func (s *memoryStore) Atomic(fn func(Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	if err := fn(&memoryStore{mu: s.mu, data: s.data, inTx: true}); err != nil {
+		*s.data = snapshot
+		return err
+	}
+	return nil
+}
+
+// lock acquires the store mutex unless the caller is already inside Atomic.
+// It returns the matching unlock function.
+func (s *memoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (d *memoryData) clone() memoryData {
+	return memoryData{
+		books:        d.books.clone(),
+		copies:       d.copies.clone(),
+		users:        d.users.clone(),
+		categories:   d.categories.clone(),
+		transactions: d.transactions.clone(),
+		reservations: d.reservations.clone(),
+		tokens:       d.tokens.clone(),
+		resets:       d.resets.clone(),
+		roles:        d.roles.clone(),
+	}
+}
+
+// table holds the rows of one entity keyed by ID.
+type table[T any] struct {
+	rows   map[uint]T
+	nextID uint
+	model  func(*T) *gorm.Model
+}
+
+func newTable[T any](model func(*T) *gorm.Model) table[T] {
+	return table[T]{rows: make(map[uint]T), nextID: 1, model: model}
+}
+
+func (t table[T]) clone() table[T] {
+	rows := make(map[uint]T, len(t.rows))
+	for id, row := range t.rows {
+		rows[id] = row
+	}
+	return table[T]{rows: rows, nextID: t.nextID, model: t.model}
+}
+
+// insert stores row with a new ID, or with its own ID when it has one. Like a
+// primary key, an ID that is already taken, even by a soft-deleted row, fails
+// with ErrDuplicate.
+func (t *table[T]) insert(row *T) error {
+	m := t.model(row)
+	if _, ok := t.rows[m.ID]; ok {
+		return ErrDuplicate
+	}
+	now := time.Now()
+	if m.ID == 0 {
+		m.ID = t.nextID
+	}
+	if m.ID >= t.nextID {
+		t.nextID = m.ID + 1
+	}
+	m.CreatedAt, m.UpdatedAt = now, now
+	t.rows[m.ID] = *row
+	return nil
+}
+
+func (t *table[T]) update(row *T) error {
+	m := t.model(row)
+	if _, ok := t.get(m.ID); !ok {
+		return ErrNotFound
+	}
+	m.UpdatedAt = time.Now()
+	t.rows[m.ID] = *row
+	return nil
+}
+
+// get returns a copy of the row unless it is missing or soft-deleted.
+func (t *table[T]) get(id uint) (T, bool) {
+	row, ok := t.rows[id]
+	if !ok || t.model(&row).DeletedAt.Valid {
+		var zero T
+		return zero, false
+	}
+	return row, true
+}
+
+func (t *table[T]) softDelete(id uint) (T, bool) {
+	row, ok := t.get(id)
+	if !ok {
+		return row, false
+	}
+	t.model(&row).DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	t.rows[id] = row
+	return row, true
+}
+
+// deleted returns the soft-deleted rows, most recently deleted first.
+func (t *table[T]) deleted() []T {
+	rows := []T{}
+	for _, row := range t.rows {
+		if t.model(&row).DeletedAt.Valid {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return t.model(&rows[i]).DeletedAt.Time.After(t.model(&rows[j]).DeletedAt.Time)
+	})
+	return rows
+}
+
+// restore clears the soft delete of the row with the given ID.
+func (t *table[T]) restore(id uint) (T, bool) {
+	row, ok := t.rows[id]
+	if !ok || !t.model(&row).DeletedAt.Valid {
+		var zero T
+		return zero, false
+	}
+	t.model(&row).DeletedAt = gorm.DeletedAt{}
+	t.rows[id] = row
+	return row, true
+}
+
+// purge permanently removes rows soft-deleted before cutoff, except those
+// keep matches, and returns their IDs. keep may be nil.
+func (t *table[T]) purge(cutoff time.Time, keep func(*T) bool) []uint {
+	var ids []uint
+	for id, row := range t.rows {
+		deletedAt := t.model(&row).DeletedAt
+		if deletedAt.Valid && deletedAt.Time.Before(cutoff) && (keep == nil || !keep(&row)) {
+			delete(t.rows, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// all returns the live rows ordered by ID, optionally filtered.
+func (t *table[T]) all(match func(*T) bool) []T {
+	rows := make([]T, 0, len(t.rows))
+	for id := range t.rows {
+		row, ok := t.get(id)
+		if ok && (match == nil || match(&row)) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return t.model(&rows[i]).ID < t.model(&rows[j]).ID })
+	return rows
+}
+
+func (t *table[T]) first(match func(*T) bool) (*T, bool) {
+	rows := t.all(match)
+	if len(rows) == 0 {
+		return nil, false
+	}
+	return &rows[0], true
+}
+
+func (t *table[T]) exists(match func(*T) bool) bool {
+	_, ok := t.first(match)
+	return ok
+}
+
+// taken reports whether any row, soft-deleted or not, matches. The unique
+// indexes of the database cover soft-deleted rows, so unique checks use it.
+func (t *table[T]) taken(match func(*T) bool) bool {
+	for _, row := range t.rows {
+		if match(&row) {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryBooks struct{ s *memoryStore }
+
+func (r memoryBooks) duplicate(book *models.Book) bool {
+	return r.s.data.books.taken(func(b *models.Book) bool { return b.ID != book.ID && b.ISBN == book.ISBN })
+}
+
+func (r memoryBooks) Create(book *models.Book) error {
+	defer r.s.lock()()
+	if r.duplicate(book) {
+		return ErrDuplicate
+	}
+	return r.s.data.books.insert(book)
+}
+
+// List fills the inventory of every book first, since availability can be filtered on.
+func (r memoryBooks) List(q ListQuery) ([]models.Book, int64, error) {
+	defer r.s.lock()()
+	books := r.s.data.books.all(nil)
+	for i := range books {
+		r.fillInventory(&books[i])
+	}
+	return memoryList(books, bookListFields, q)
+}
+
+// fillInventory sets the computed inventory fields from the copies table.
+func (r memoryBooks) fillInventory(book *models.Book) {
+	counts := map[models.CopyStatus]int{}
+	for _, c := range r.s.data.copies.all(func(c *models.BookCopy) bool { return c.BookID == book.ID }) {
+		counts[c.Status]++
+	}
+	book.SetInventory(counts)
+}
+
+// FindForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryBooks) FindForUpdate(id uint) (*models.Book, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryBooks) FindByID(id uint) (*models.Book, error) {
+	defer r.s.lock()()
+	book, ok := r.s.data.books.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r.fillInventory(&book)
+	return &book, nil
+}
+
+func (r memoryBooks) FindByIDs(ids []uint) ([]models.Book, error) {
+	defer r.s.lock()()
+	books := make([]models.Book, 0, len(ids))
+	for _, id := range ids {
+		if book, ok := r.s.data.books.get(id); ok {
+			r.fillInventory(&book)
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (r memoryBooks) Update(book *models.Book) error {
+	defer r.s.lock()()
+	if r.duplicate(book) {
+		return ErrDuplicate
+	}
+	return r.s.data.books.update(book)
+}
+
+func (r memoryBooks) Delete(id uint) (*models.Book, error) {
+	defer r.s.lock()()
+	book, ok := r.s.data.books.softDelete(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r.fillInventory(&book)
+	return &book, nil
+}
+
+func (r memoryBooks) CountByCategory(categoryID uint) (int64, error) {
+	defer r.s.lock()()
+	return int64(len(r.s.data.books.all(func(b *models.Book) bool { return b.CategoryID == categoryID }))), nil
+}
+
+func (r memoryBooks) ListDeleted() ([]models.Book, error) {
+	defer r.s.lock()()
+	books := r.s.data.books.deleted()
+	for i := range books {
+		r.fillInventory(&books[i])
+	}
+	return books, nil
+}
+
+func (r memoryBooks) Restore(id uint) (*models.Book, error) {
+	defer r.s.lock()()
+	row, ok := r.s.data.books.rows[id]
+	if ok && r.duplicate(&row) {
+		return nil, ErrDuplicate
+	}
+	book, ok := r.s.data.books.restore(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r.fillInventory(&book)
+	return &book, nil
+}
+
+// PurgeDeletedBefore also removes the copies of the purged books.
+func (r memoryBooks) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	defer r.s.lock()()
+	circulating := map[uint]bool{}
+	for _, c := range r.s.data.copies.all(inCirculation) {
+		circulating[c.BookID] = true
+	}
+	ids := r.s.data.books.purge(cutoff, func(b *models.Book) bool { return circulating[b.ID] })
+	for _, id := range ids {
+		for copyID, c := range r.s.data.copies.rows {
+			if c.BookID == id {
+				delete(r.s.data.copies.rows, copyID)
+			}
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+// inCirculation matches copies on loan or on hold, which loans and
+// reservations refer to.
+func inCirculation(c *models.BookCopy) bool {
+	return c.Status == models.CopyBorrowed || c.Status == models.CopyReserved
+}
+
+type memoryCopies struct{ s *memoryStore }
+
+func (r memoryCopies) Create(bookCopy *models.BookCopy) error {
+	defer r.s.lock()()
+	if r.s.data.copies.taken(func(c *models.BookCopy) bool { return c.Barcode == bookCopy.Barcode }) {
+		return ErrDuplicate
+	}
+	return r.s.data.copies.insert(bookCopy)
+}
+
+func (r memoryCopies) FindByID(id uint) (*models.BookCopy, error) {
+	defer r.s.lock()()
+	bookCopy, ok := r.s.data.copies.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &bookCopy, nil
+}
+
+func (r memoryCopies) FindByBarcode(barcode string) (*models.BookCopy, error) {
+	defer r.s.lock()()
+	bookCopy, ok := r.s.data.copies.first(func(c *models.BookCopy) bool { return c.Barcode == barcode })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bookCopy, nil
+}
+
+// FindForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryCopies) FindForUpdate(id uint) (*models.BookCopy, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryCopies) ListByBook(bookID uint) ([]models.BookCopy, error) {
+	defer r.s.lock()()
+	return r.s.data.copies.all(func(c *models.BookCopy) bool { return c.BookID == bookID }), nil
+}
+
+func (r memoryCopies) CountAllByBook(bookID uint) (int64, error) {
+	defer r.s.lock()()
+	var count int64
+	for _, c := range r.s.data.copies.rows {
+		if c.BookID == bookID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memoryCopies) FirstAvailable(bookID uint) (*models.BookCopy, error) {
+	defer r.s.lock()()
+	bookCopy, ok := r.s.data.copies.first(func(c *models.BookCopy) bool {
+		return c.BookID == bookID && c.Status == models.CopyAvailable
+	})
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bookCopy, nil
+}
+
+func (r memoryCopies) Update(bookCopy *models.BookCopy) error {
+	defer r.s.lock()()
+	if r.s.data.copies.taken(func(c *models.BookCopy) bool { return c.ID != bookCopy.ID && c.Barcode == bookCopy.Barcode }) {
+		return ErrDuplicate
+	}
+	return r.s.data.copies.update(bookCopy)
+}
+
+func (r memoryCopies) Delete(id uint) error {
+	defer r.s.lock()()
+	if _, ok := r.s.data.copies.softDelete(id); !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryUsers struct{ s *memoryStore }
+
+func (r memoryUsers) duplicate(user *models.User) bool {
+	return r.s.data.users.taken(func(u *models.User) bool {
+		return u.ID != user.ID && (u.Email == user.Email || u.MembershipID == user.MembershipID)
+	})
+}
+
+func (r memoryUsers) Create(user *models.User) error {
+	defer r.s.lock()()
+	if r.duplicate(user) {
+		return ErrDuplicate
+	}
+	// Run the same hook GORM would, so passwords are hashed in memory too.
+	if err := user.BeforeSave(nil); err != nil {
+		return err
+	}
+	return r.s.data.users.insert(user)
+}
+
+func (r memoryUsers) List(q ListQuery) ([]models.User, int64, error) {
+	defer r.s.lock()()
+	return memoryList(r.s.data.users.all(nil), userListFields, q)
+}
+
+// FindForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryUsers) FindForUpdate(id uint) (*models.User, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryUsers) FindByID(id uint) (*models.User, error) {
+	defer r.s.lock()()
+	user, ok := r.s.data.users.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryUsers) FindByEmail(email string) (*models.User, error) {
+	defer r.s.lock()()
+	user, ok := r.s.data.users.first(func(u *models.User) bool { return u.Email == email })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r memoryUsers) Update(user *models.User) error {
+	defer r.s.lock()()
+	if r.duplicate(user) {
+		return ErrDuplicate
+	}
+	if err := user.BeforeSave(nil); err != nil {
+		return err
+	}
+	return r.s.data.users.update(user)
+}
+
+func (r memoryUsers) Delete(id uint) (*models.User, error) {
+	defer r.s.lock()()
+	user, ok := r.s.data.users.softDelete(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryUsers) ListDeleted() ([]models.User, error) {
+	defer r.s.lock()()
+	return r.s.data.users.deleted(), nil
+}
+
+func (r memoryUsers) Restore(id uint) (*models.User, error) {
+	defer r.s.lock()()
+	row, ok := r.s.data.users.rows[id]
+	if ok && r.duplicate(&row) {
+		return nil, ErrDuplicate
+	}
+	user, ok := r.s.data.users.restore(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryUsers) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	defer r.s.lock()()
+	return int64(len(r.s.data.users.purge(cutoff, nil))), nil
+}
+
+type memoryCategories struct{ s *memoryStore }
+
+func (r memoryCategories) duplicate(category *models.Category) bool {
+	return r.s.data.categories.taken(func(c *models.Category) bool { return c.ID != category.ID && c.Name == category.Name })
+}
+
+func (r memoryCategories) Create(category *models.Category) error {
+	defer r.s.lock()()
+	if r.duplicate(category) {
+		return ErrDuplicate
+	}
+	return r.s.data.categories.insert(category)
+}
+
+func (r memoryCategories) List(q ListQuery) ([]models.Category, int64, error) {
+	defer r.s.lock()()
+	return memoryList(r.s.data.categories.all(nil), categoryListFields, q)
+}
+
+func (r memoryCategories) FindForUpdate(id uint) (*models.Category, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryCategories) FindByID(id uint) (*models.Category, error) {
+	defer r.s.lock()()
+	category, ok := r.s.data.categories.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+func (r memoryCategories) Update(category *models.Category) error {
+	defer r.s.lock()()
+	if r.duplicate(category) {
+		return ErrDuplicate
+	}
+	return r.s.data.categories.update(category)
+}
+
+func (r memoryCategories) Delete(id uint) error {
+	defer r.s.lock()()
+	if _, ok := r.s.data.categories.softDelete(id); !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r memoryCategories) ListDeleted() ([]models.Category, error) {
+	defer r.s.lock()()
+	return r.s.data.categories.deleted(), nil
+}
+
+func (r memoryCategories) Restore(id uint) (*models.Category, error) {
+	defer r.s.lock()()
+	row, ok := r.s.data.categories.rows[id]
+	if ok && r.duplicate(&row) {
+		return nil, ErrDuplicate
+	}
+	category, ok := r.s.data.categories.restore(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+func (r memoryCategories) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	defer r.s.lock()()
+	return int64(len(r.s.data.categories.purge(cutoff, nil))), nil
+}
+
+type memoryTransactions struct{ s *memoryStore }
+
+func (r memoryTransactions) Create(transaction *models.Transaction) error {
+	defer r.s.lock()()
+	return r.s.data.transactions.insert(transaction)
+}
+
+// FindForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryTransactions) FindForUpdate(id uint) (*models.Transaction, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryTransactions) FindByID(id uint) (*models.Transaction, error) {
+	defer r.s.lock()()
+	transaction, ok := r.s.data.transactions.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &transaction, nil
+}
+
+func (r memoryTransactions) Update(transaction *models.Transaction) error {
+	defer r.s.lock()()
+	return r.s.data.transactions.update(transaction)
+}
+
+func (r memoryTransactions) CountActiveByUser(userID uint) (int64, error) {
+	defer r.s.lock()()
+	active := r.s.data.transactions.all(func(t *models.Transaction) bool { return t.UserID == userID && t.ReturnDate == nil && t.LostAt == nil })
+	return int64(len(active)), nil
+}
+
+type memoryReservations struct{ s *memoryStore }
+
+func (r memoryReservations) Create(reservation *models.Reservation) error {
+	defer r.s.lock()()
+	return r.s.data.reservations.insert(reservation)
+}
+
+func (r memoryReservations) FindPending(userID, bookID uint) (*models.Reservation, error) {
+	defer r.s.lock()()
+	reservation, ok := r.s.data.reservations.first(func(res *models.Reservation) bool {
+		return res.UserID == userID && res.BookID == bookID && res.Status == "Pending"
+	})
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return reservation, nil
+}
+
+func (r memoryReservations) FindHeld(userID, bookID uint) (*models.Reservation, error) {
+	defer r.s.lock()()
+	reservation, ok := r.s.data.reservations.first(func(res *models.Reservation) bool {
+		return res.UserID == userID && res.BookID == bookID && res.Status == "Fulfilled" && res.CopyID != nil
+	})
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return reservation, nil
+}
+
+func (r memoryReservations) OldestPendingForBook(bookID uint) (*models.Reservation, error) {
+	defer r.s.lock()()
+	pending := r.s.data.reservations.all(func(res *models.Reservation) bool {
+		return res.BookID == bookID && res.Status == "Pending"
+	})
+	if len(pending) == 0 {
+		return nil, ErrNotFound
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	return &pending[0], nil
+}
+
+func (r memoryReservations) Update(reservation *models.Reservation) error {
+	defer r.s.lock()()
+	return r.s.data.reservations.update(reservation)
+}
+
+type memoryRefreshTokens struct{ s *memoryStore }
+
+func (r memoryRefreshTokens) Create(token *models.RefreshToken) error {
+	defer r.s.lock()()
+	if r.s.data.tokens.taken(func(t *models.RefreshToken) bool { return t.TokenHash == token.TokenHash }) {
+		return ErrDuplicate
+	}
+	return r.s.data.tokens.insert(token)
+}
+
+// FindByHashForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryRefreshTokens) FindByHashForUpdate(hash string) (*models.RefreshToken, error) {
+	defer r.s.lock()()
+	token, ok := r.s.data.tokens.first(func(t *models.RefreshToken) bool { return t.TokenHash == hash })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return token, nil
+}
+
+func (r memoryRefreshTokens) Update(token *models.RefreshToken) error {
+	defer r.s.lock()()
+	return r.s.data.tokens.update(token)
+}
+
+func (r memoryRefreshTokens) SessionActive(sessionID string, now time.Time) (bool, error) {
+	defer r.s.lock()()
+	return r.s.data.tokens.exists(func(t *models.RefreshToken) bool {
+		return t.SessionID == sessionID && t.RevokedAt == nil && t.ExpiresAt.After(now)
+	}), nil
+}
+
+func (r memoryRefreshTokens) RevokeSession(sessionID string, at time.Time) error {
+	return r.revoke(func(t *models.RefreshToken) bool { return t.SessionID == sessionID }, at)
+}
+
+func (r memoryRefreshTokens) RevokeUser(userID uint, at time.Time) error {
+	return r.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID }, at)
+}
+
+func (r memoryRefreshTokens) RevokeOtherSessions(userID uint, keep string, at time.Time) error {
+	return r.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID && t.SessionID != keep }, at)
+}
+
+func (r memoryRefreshTokens) revoke(match func(*models.RefreshToken) bool, at time.Time) error {
+	defer r.s.lock()()
+	for _, token := range r.s.data.tokens.all(match) {
+		if token.RevokedAt == nil {
+			token.RevokedAt = &at
+			r.s.data.tokens.rows[token.ID] = token
+		}
+	}
+	return nil
+}
+
+func (r memoryRefreshTokens) PurgeExpiredBefore(cutoff time.Time) (int64, error) {
+	defer r.s.lock()()
+	var n int64
+	for id, token := range r.s.data.tokens.rows {
+		if token.ExpiresAt.Before(cutoff) {
+			delete(r.s.data.tokens.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memoryPasswordResets struct{ s *memoryStore }
+
+func (r memoryPasswordResets) Create(reset *models.PasswordReset) error {
+	defer r.s.lock()()
+	if r.s.data.resets.taken(func(p *models.PasswordReset) bool { return p.TokenHash == reset.TokenHash }) {
+		return ErrDuplicate
+	}
+	return r.s.data.resets.insert(reset)
+}
+
+// FindByHashForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryPasswordResets) FindByHashForUpdate(hash string) (*models.PasswordReset, error) {
+	defer r.s.lock()()
+	reset, ok := r.s.data.resets.first(func(p *models.PasswordReset) bool { return p.TokenHash == hash })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return reset, nil
+}
+
+func (r memoryPasswordResets) UseAll(userID uint, at time.Time) error {
+	defer r.s.lock()()
+	for _, reset := range r.s.data.resets.all(func(p *models.PasswordReset) bool { return p.UserID == userID && p.UsedAt == nil }) {
+		reset.UsedAt = &at
+		r.s.data.resets.rows[reset.ID] = reset
+	}
+	return nil
+}
+
+func (r memoryPasswordResets) CountIssuedSince(userID uint, since time.Time) (int64, error) {
+	defer r.s.lock()()
+	issued := r.s.data.resets.all(func(p *models.PasswordReset) bool {
+		return p.UserID == userID && !p.CreatedAt.Before(since)
+	})
+	return int64(len(issued)), nil
+}
+
+func (r memoryPasswordResets) PurgeExpiredBefore(cutoff time.Time) (int64, error) {
+	defer r.s.lock()()
+	var n int64
+	for id, reset := range r.s.data.resets.rows {
+		if reset.ExpiresAt.Before(cutoff) {
+			delete(r.s.data.resets.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// memoryRoles copies permission slices in and out so callers never share
+// them with the stored rows.
+type memoryRoles struct{ s *memoryStore }
+
+func (r memoryRoles) Create(role *models.Role) error {
+	defer r.s.lock()()
+	if r.s.data.roles.taken(func(existing *models.Role) bool { return existing.Name == role.Name }) {
+		return ErrDuplicate
+	}
+	stored := *role
+	stored.Permissions = slices.Clone(role.Permissions)
+	if err := r.s.data.roles.insert(&stored); err != nil {
+		return err
+	}
+	role.Model = stored.Model
+	return nil
+}
+
+func (r memoryRoles) List() ([]models.Role, error) {
+	defer r.s.lock()()
+	roles := r.s.data.roles.all(nil)
+	for i := range roles {
+		roles[i].Permissions = sortedPermissions(roles[i].Permissions)
+	}
+	return roles, nil
+}
+
+func (r memoryRoles) FindByID(id uint) (*models.Role, error) {
+	defer r.s.lock()()
+	role, ok := r.s.data.roles.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	role.Permissions = sortedPermissions(role.Permissions)
+	return &role, nil
+}
+
+func (r memoryRoles) FindByName(name string) (*models.Role, error) {
+	defer r.s.lock()()
+	role, ok := r.s.data.roles.first(func(role *models.Role) bool { return role.Name == name })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	role.Permissions = sortedPermissions(role.Permissions)
+	return role, nil
+}
+
+// FindForUpdate needs no extra locking: Atomic already holds the store lock.
+func (r memoryRoles) FindForUpdate(id uint) (*models.Role, error) {
+	return r.FindByID(id)
+}
+
+func (r memoryRoles) Update(role *models.Role) error {
+	defer r.s.lock()()
+	stored, ok := r.s.data.roles.get(role.ID)
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Description = role.Description
+	stored.Permissions = slices.Clone(role.Permissions)
+	if err := r.s.data.roles.update(&stored); err != nil {
+		return err
+	}
+	role.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r memoryRoles) Delete(id uint) error {
+	defer r.s.lock()()
+	if _, ok := r.s.data.roles.get(id); !ok {
+		return ErrNotFound
+	}
+	delete(r.s.data.roles.rows, id)
+	return nil
+}
+
+// sortedPermissions returns a sorted copy, matching the order the GORM store
+// loads permissions in.
+func sortedPermissions(permissions []string) []string {
+	sorted := append([]string{}, permissions...)
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,180 @@
+// Package repository defines the data access interfaces used by the controllers,
+// together with a GORM implementation and an in-memory implementation for tests.
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a create or update violates a unique field.
+	ErrDuplicate = errors.New("duplicate record")
+	// ErrInvalidReference is returned when a write refers to a record that does not exist.
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+// Store groups the repositories for every entity.
+type Store interface {
+	Books() BookRepository
+	Copies() CopyRepository
+	Users() UserRepository
+	Categories() CategoryRepository
+	Transactions() TransactionRepository
+	Reservations() ReservationRepository
+	RefreshTokens() RefreshTokenRepository
+	PasswordResets() PasswordResetRepository
+	Roles() RoleRepository
+
+	// Atomic runs fn inside a single database transaction. The Store passed to fn
+	// must be used for every read and write that belongs to the transaction; if fn
+	// returns an error all of its writes are rolled back.
+	Atomic(fn func(Store) error) error
+}
+
+// Trash gives access to soft-deleted records of type T.
+type Trash[T any] interface {
+	// ListDeleted returns the soft-deleted records, most recently deleted first.
+	ListDeleted() ([]T, error)
+	// Restore undoes the soft delete of the record with the given ID.
+	Restore(id uint) (*T, error)
+	// PurgeDeletedBefore permanently removes records soft-deleted before cutoff.
+	PurgeDeletedBefore(cutoff time.Time) (int64, error)
+}
+
+type BookRepository interface {
+	Trash[models.Book]
+
+	Create(book *models.Book) error
+	// List returns the page of books selected by q and the number of books matching its filters.
+	List(q ListQuery) ([]models.Book, int64, error)
+	FindByID(id uint) (*models.Book, error)
+	// FindByIDs returns the books with the given IDs in the same order, skipping missing ones.
+	FindByIDs(ids []uint) ([]models.Book, error)
+	// FindForUpdate loads the book and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.Book, error)
+	Update(book *models.Book) error
+	Delete(id uint) (*models.Book, error)
+	CountByCategory(categoryID uint) (int64, error)
+}
+
+// CopyRepository manages the physical copies of books. Book reads fill the
+// computed inventory fields (Book.Copies, Book.AvailableCount, ...) from these rows.
+type CopyRepository interface {
+	Create(bookCopy *models.BookCopy) error
+	FindByID(id uint) (*models.BookCopy, error)
+	FindByBarcode(barcode string) (*models.BookCopy, error)
+	// FindForUpdate loads the copy and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.BookCopy, error)
+	ListByBook(bookID uint) ([]models.BookCopy, error)
+	// CountAllByBook counts the book's copies, including deleted ones.
+	CountAllByBook(bookID uint) (int64, error)
+	// FirstAvailable returns the book's oldest copy that is on the shelf.
+	FirstAvailable(bookID uint) (*models.BookCopy, error)
+	Update(bookCopy *models.BookCopy) error
+	Delete(id uint) error
+}
+
+type UserRepository interface {
+	Trash[models.User]
+
+	Create(user *models.User) error
+	// List returns the page of users selected by q and the number of users matching its filters.
+	List(q ListQuery) ([]models.User, int64, error)
+	FindByID(id uint) (*models.User, error)
+	// FindForUpdate loads the user and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	Update(user *models.User) error
+	Delete(id uint) (*models.User, error)
+}
+
+type CategoryRepository interface {
+	Trash[models.Category]
+
+	Create(category *models.Category) error
+	// List returns the page of categories selected by q and the number of categories matching its filters.
+	List(q ListQuery) ([]models.Category, int64, error)
+	FindByID(id uint) (*models.Category, error)
+	// FindForUpdate loads the category and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(id uint) error
+}
+
+type TransactionRepository interface {
+	Create(transaction *models.Transaction) error
+	FindByID(id uint) (*models.Transaction, error)
+	// FindForUpdate loads the transaction and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.Transaction, error)
+	Update(transaction *models.Transaction) error
+	// CountActiveByUser counts the user's open loans: not returned yet and not
+	// closed as lost.
+	CountActiveByUser(userID uint) (int64, error)
+}
+
+type ReservationRepository interface {
+	Create(reservation *models.Reservation) error
+	// FindPending returns the user's pending reservation for the book, if any.
+	FindPending(userID, bookID uint) (*models.Reservation, error)
+	// FindHeld returns the user's fulfilled reservation for the book, whose copy is on hold for them.
+	FindHeld(userID, bookID uint) (*models.Reservation, error)
+	// OldestPendingForBook returns the first reservation in the book's queue.
+	OldestPendingForBook(bookID uint) (*models.Reservation, error)
+	Update(reservation *models.Reservation) error
+}
+
+// RefreshTokenRepository stores the refresh tokens of login sessions.
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	// FindByHashForUpdate loads the token with the given hash and locks its
+	// row until the surrounding Atomic call ends.
+	FindByHashForUpdate(hash string) (*models.RefreshToken, error)
+	Update(token *models.RefreshToken) error
+	// SessionActive reports whether the session has a token that is neither
+	// revoked nor expired at now.
+	SessionActive(sessionID string, now time.Time) (bool, error)
+	// RevokeSession revokes every token of the session.
+	RevokeSession(sessionID string, at time.Time) error
+	// RevokeUser revokes every token of every session of the user.
+	RevokeUser(userID uint, at time.Time) error
+	// RevokeOtherSessions revokes every token of the user outside the session keep.
+	RevokeOtherSessions(userID uint, keep string, at time.Time) error
+	// PurgeExpiredBefore permanently removes tokens that expired before cutoff.
+	PurgeExpiredBefore(cutoff time.Time) (int64, error)
+}
+
+// PasswordResetRepository stores the single-use tokens that reset a password.
+type PasswordResetRepository interface {
+	Create(reset *models.PasswordReset) error
+	// FindByHashForUpdate loads the token with the given hash and locks its
+	// row until the surrounding Atomic call ends.
+	FindByHashForUpdate(hash string) (*models.PasswordReset, error)
+	// UseAll marks every unused token of the user used, so none of them can
+	// reset the password any more.
+	UseAll(userID uint, at time.Time) error
+	// CountIssuedSince counts the user's tokens created at or after since,
+	// used or not.
+	CountIssuedSince(userID uint, since time.Time) (int64, error)
+	// PurgeExpiredBefore permanently removes tokens that expired before cutoff.
+	PurgeExpiredBefore(cutoff time.Time) (int64, error)
+}
+
+// RoleRepository stores roles together with the permissions they grant.
+// Roles are deleted permanently rather than moved to the trash.
+type RoleRepository interface {
+	Create(role *models.Role) error
+	// List returns every role ordered by ID.
+	List() ([]models.Role, error)
+	FindByID(id uint) (*models.Role, error)
+	FindByName(name string) (*models.Role, error)
+	// FindForUpdate loads the role and locks its row until the surrounding Atomic call ends.
+	FindForUpdate(id uint) (*models.Role, error)
+	// Update saves the role's description and replaces its permissions.
+	Update(role *models.Role) error
+	Delete(id uint) error
+}
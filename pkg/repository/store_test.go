@@ -0,0 +1,194 @@
+package repository_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/migrations"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+)
+
+// eachStore runs test against the memory store and a migrated SQLite
+// database, so both implementations are held to the same behaviour.
+func eachStore(t *testing.T, test func(t *testing.T, store repository.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := config.Open(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrations.Up(db); err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		test(t, repository.NewGormStore(db))
+	})
+}
+
+func newUser(t *testing.T, store repository.Store, email string) *models.User {
+	t.Helper()
+	user := &models.User{Name: "Reader", Email: email, Password: "password", MembershipID: email, Role: models.DefaultRole}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCreateAndFind(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		user := newUser(t, store, "ada@example.com")
+		if user.ID == 0 || user.CreatedAt.IsZero() {
+			t.Fatalf("Create() left ID %d and CreatedAt %v unset", user.ID, user.CreatedAt)
+		}
+		found, err := store.Users().FindByEmail("ada@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.ID != user.ID || found.Password == "password" {
+			t.Errorf("FindByEmail() = user %d with password %q, want user %d with a hashed password", found.ID, found.Password, user.ID)
+		}
+		if _, err := store.Users().FindByID(user.ID + 100); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID() of a missing user = %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+}
+
+func TestUniqueFields(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		newUser(t, store, "ada@example.com")
+		other := newUser(t, store, "grace@example.com")
+
+		duplicate := &models.User{Name: "Ada", Email: "ada@example.com", Password: "password", MembershipID: "M2"}
+		if err := store.Users().Create(duplicate); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("Create() with a taken email = %v, want %v", err, repository.ErrDuplicate)
+		}
+		other.Email = "ada@example.com"
+		if err := store.Users().Update(other); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("Update() to a taken email = %v, want %v", err, repository.ErrDuplicate)
+		}
+
+		sameID := &models.User{Name: "Eve", Email: "eve@example.com", Password: "password", MembershipID: "M3"}
+		sameID.ID = other.ID
+		if err := store.Users().Create(sameID); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("Create() with a taken ID = %v, want %v", err, repository.ErrDuplicate)
+		}
+		if found, err := store.Users().FindByID(other.ID); err != nil || found.Email != "grace@example.com" {
+			t.Errorf("FindByID() after a Create() with its ID = %v, %v; want the user unchanged", found, err)
+		}
+
+		// Unique fields stay taken while a record is in the trash.
+		if _, err := store.Users().Delete(other.ID); err != nil {
+			t.Fatal(err)
+		}
+		reuse := &models.User{Name: "Grace", Email: "grace@example.com", Password: "password", MembershipID: "M4"}
+		if err := store.Users().Create(reuse); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("Create() with the email of a deleted user = %v, want %v", err, repository.ErrDuplicate)
+		}
+	})
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		user := newUser(t, store, "ada@example.com")
+		if _, err := store.Users().Delete(user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Users().FindByID(user.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("FindByID() of a deleted user = %v, want %v", err, repository.ErrNotFound)
+		}
+		deleted, err := store.Users().ListDeleted()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted) != 1 || deleted[0].ID != user.ID {
+			t.Fatalf("ListDeleted() = %d users, want user %d", len(deleted), user.ID)
+		}
+
+		if _, err := store.Users().Restore(user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Users().FindByID(user.ID); err != nil {
+			t.Errorf("FindByID() of a restored user = %v", err)
+		}
+
+		if _, err := store.Users().Delete(user.ID); err != nil {
+			t.Fatal(err)
+		}
+		purged, err := store.Users().PurgeDeletedBefore(time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged != 1 {
+			t.Errorf("PurgeDeletedBefore() = %d, want 1", purged)
+		}
+		if _, err := store.Users().Restore(user.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Restore() of a purged user = %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+}
+
+func TestAtomicRollsBack(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		user := newUser(t, store, "ada@example.com")
+		failed := errors.New("failed")
+		err := store.Atomic(func(tx repository.Store) error {
+			if err := tx.Users().Create(&models.User{Name: "Grace", Email: "grace@example.com", Password: "password", MembershipID: "M2"}); err != nil {
+				return err
+			}
+			user.Name = "Changed"
+			if err := tx.Users().Update(user); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("Atomic() = %v, want %v", err, failed)
+		}
+		if _, err := store.Users().FindByEmail("grace@example.com"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("a user created in a failed Atomic call was kept: %v", err)
+		}
+		found, err := store.Users().FindByID(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Name != "Reader" {
+			t.Errorf("an update in a failed Atomic call was kept: name is %q", found.Name)
+		}
+	})
+}
+
+func TestCountActiveByUser(t *testing.T) {
+	eachStore(t, func(t *testing.T, store repository.Store) {
+		user := newUser(t, store, "ada@example.com")
+		now := time.Now()
+		loans := []*models.Transaction{
+			{UserID: user.ID, BookID: 1, CopyID: 1},
+			{UserID: user.ID, BookID: 1, CopyID: 2, ReturnDate: &now},
+			{UserID: user.ID, BookID: 1, CopyID: 3, LostAt: &now},
+			{UserID: user.ID + 1, BookID: 1, CopyID: 4},
+		}
+		for _, loan := range loans {
+			loan.BorrowDate, loan.DueDate = now, now
+			if err := store.Transactions().Create(loan); err != nil {
+				t.Fatal(err)
+			}
+		}
+		active, err := store.Transactions().CountActiveByUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != 1 {
+			t.Errorf("CountActiveByUser() = %d, want 1", active)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package repository
+
+import "time"
+
+// PurgeResult counts the records removed by PurgeTrash.
+type PurgeResult struct {
+	Books      int64 `json:"books"`
+	Users      int64 `json:"users"`
+	Categories int64 `json:"categories"`
+}
+
+// PurgeTrash permanently removes books, users and categories that were
+// soft-deleted before cutoff, all in one transaction.
+func PurgeTrash(s Store, cutoff time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	err := s.Atomic(func(tx Store) error {
+		var err error
+		if result.Books, err = tx.Books().PurgeDeletedBefore(cutoff); err != nil {
+			return err
+		}
+		if result.Users, err = tx.Users().PurgeDeletedBefore(cutoff); err != nil {
+			return err
+		}
+		result.Categories, err = tx.Categories().PurgeDeletedBefore(cutoff)
+		return err
+	})
+	return result, err
+}
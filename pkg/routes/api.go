@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+	"github.com/gorilla/mux"
+)
+
+// Version is one version of the API, served under /api/<Name>. Versions are
+// independent route sets, so a v2 can change payloads while v1 keeps serving
+// existing clients.
+type Version struct {
+	Name string
+	// Routes builds the version's route table on h.
+	Routes func(h *controllers.Handler) []Route
+}
+
+// Prefix is the path the version is served under.
+func (v Version) Prefix() string {
+	return "/api/" + v.Name
+}
+
+// Register adds v's routes, served by h, to router, each behind the
+// middleware from auth that its policy requires. Nothing is registered if
+// the table fails Verify.
+func (v Version) Register(router *mux.Router, h *controllers.Handler, auth *middleware.Auth) error {
+	return v.register(router, "", h, auth)
+}
+
+// register is Register with prefix in front of every path.
+func (v Version) register(router *mux.Router, prefix string, h *controllers.Handler, auth *middleware.Auth) error {
+	table := v.Routes(h)
+	if err := Verify(table); err != nil {
+		return err
+	}
+	for _, rt := range table {
+		router.Handle(prefix+rt.Path, rt.Policy.wrap(auth, rt.Handler)).Methods(rt.Method)
+	}
+	return nil
+}
+
+// Bodies returns the request and response types of v's routes, keyed by
+// "METHOD path", for openapi.Check.
+func (v Version) Bodies() map[string]openapi.Body {
+	bodies := map[string]openapi.Body{}
+	for _, rt := range v.Routes(&controllers.Handler{}) {
+		bodies[rt.Method+" "+rt.Path] = rt.Body
+	}
+	return bodies
+}
+
+// Versions are the API versions served side by side. Serve a v2 alongside v1
+// by appending Version{Name: "v2", Routes: v2Routes}.
+var Versions = []Version{V1}
+
+// Mount registers each version under its prefix. The routes are added to
+// router with their full paths rather than to a PathPrefix subrouter: the
+// subrouter's routes all match the prefix, which makes mux forget a method
+// mismatch and answer a wrong method with 404 instead of 405.
+func Mount(router *mux.Router, h *controllers.Handler, auth *middleware.Auth, versions ...Version) error {
+	for _, v := range versions {
+		if err := v.register(router, v.Prefix(), h, auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MountLegacy also serves v at the root of router, as before versioning.
+// Responses announce the deprecation and sunset dates and link to the same
+// path under v's prefix.
+func MountLegacy(router *mux.Router, h *controllers.Handler, auth *middleware.Auth, v Version, deprecated, sunset time.Time) error {
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(middleware.Deprecated(v.Prefix(), deprecated, sunset))
+	return v.Register(legacy, h, auth)
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"reflect"
+
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+)
+
+// None stands in Types for a request or response without a body.
+type None struct{}
+
+// Types records that a route's handler decodes a Req from the request body
+// and encodes a Resp in a successful response, e.g. Types[None, []models.Book]
+// for a list. openapi.Check compares them with the documented operation, so a
+// handler that changes what it reads or writes must change its route, and the
+// document, too.
+func Types[Req, Resp any]() openapi.Body {
+	return openapi.Body{Request: bodyType[Req](), Response: bodyType[Resp]()}
+}
+
+func bodyType[T any]() reflect.Type {
+	t := reflect.TypeFor[T]()
+	if t == reflect.TypeFor[None]() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+)
+
+// Route is one endpoint of a version, who may call it, and the types its
+// handler reads and writes.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+	Policy  Policy
+	Body    openapi.Body
+}
+
+// Policy says who may call a route. The zero Policy means none was declared,
+// which Verify rejects.
+type Policy struct {
+	kind        policyKind
+	permissions []string
+}
+
+type policyKind int
+
+const (
+	undeclared policyKind = iota
+	public
+	signedIn
+	permission
+)
+
+// Public routes can be called without a token.
+var Public = Policy{kind: public}
+
+// SignedIn routes need a valid token. The handler decides what the caller may
+// do, e.g. patrons may only read and edit their own record.
+var SignedIn = Policy{kind: signedIn}
+
+// Permission routes need a valid token whose role grants any of perms.
+func Permission(perms ...string) Policy {
+	return Policy{kind: permission, permissions: perms}
+}
+
+func (p Policy) String() string {
+	switch p.kind {
+	case public:
+		return "public"
+	case signedIn:
+		return "signed in"
+	case permission:
+		return strings.Join(p.permissions, " or ")
+	}
+	return "undeclared"
+}
+
+// wrap puts the middleware enforcing p, built from auth, in front of h.
+func (p Policy) wrap(auth *middleware.Auth, h http.Handler) http.Handler {
+	switch p.kind {
+	case signedIn:
+		return auth.JWTMiddleware(h)
+	case permission:
+		return auth.JWTMiddleware(auth.RequirePermission(p.permissions...)(h))
+	}
+	return h
+}
+
+// mutating are the methods that change state.
+var mutating = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Verify reports every route that lacks an explicit policy, names a
+// permission that does not exist, or is declared twice. A route that changes
+// state and is open to everyone must say so with Public.
+func Verify(table []Route) error {
+	var problems []string
+	seen := map[string]bool{}
+	for _, rt := range table {
+		key := rt.Method + " " + rt.Path
+		if seen[key] {
+			problems = append(problems, key+" is declared twice")
+		}
+		seen[key] = true
+		if rt.Handler == nil {
+			problems = append(problems, key+" has no handler")
+		}
+
+		switch rt.Policy.kind {
+		case undeclared:
+			if slices.Contains(mutating, rt.Method) {
+				problems = append(problems, key+" changes state but declares no policy")
+			} else {
+				problems = append(problems, key+" declares no policy")
+			}
+		case permission:
+			if len(rt.Policy.permissions) == 0 {
+				problems = append(problems, key+" requires a permission but names none")
+			}
+			for _, p := range rt.Policy.permissions {
+				if !knownPermission(p) {
+					problems = append(problems, fmt.Sprintf("%s requires %q, which is not a permission", key, p))
+				}
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("routes: insecure route table:\n  " + strings.Join(problems, "\n  "))
+}
+
+func knownPermission(name string) bool {
+	for _, p := range models.Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
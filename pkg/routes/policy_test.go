@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/middleware"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+var noBody = Types[None, None]()
+
+func TestV1DeclaresEveryPolicy(t *testing.T) {
+	if err := Verify(V1.Routes(&controllers.Handler{})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRejectsInsecureTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		table []Route
+		want  string
+	}{
+		{"undeclared write", []Route{{"POST", "/x", noop, Policy{}, noBody}}, "POST /x changes state but declares no policy"},
+		{"undeclared read", []Route{{"GET", "/x", noop, Policy{}, noBody}}, "GET /x declares no policy"},
+		{"duplicate", []Route{{"GET", "/x", noop, Public, noBody}, {"GET", "/x", noop, SignedIn, noBody}}, "GET /x is declared twice"},
+		{"unknown permission", []Route{{"PUT", "/x", noop, Permission("books:burn"), noBody}}, `PUT /x requires "books:burn", which is not a permission`},
+		{"no permission", []Route{{"PUT", "/x", noop, Permission(), noBody}}, "PUT /x requires a permission but names none"},
+		{"no handler", []Route{{"GET", "/x", nil, Public, noBody}}, "GET /x has no handler"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.table)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsDeclaredPolicies(t *testing.T) {
+	table := []Route{
+		{"POST", "/x", noop, Public, noBody},
+		{"GET", "/x", noop, SignedIn, noBody},
+		{"PUT", "/x", noop, Permission(models.PermBooksWrite, models.PermUsersWrite), noBody},
+	}
+	if err := Verify(table); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterRefusesInsecureTable(t *testing.T) {
+	v := Version{Name: "test", Routes: func(*controllers.Handler) []Route {
+		return []Route{{"GET", "/ok", noop, Public, noBody}, {"POST", "/x", noop, Policy{}, noBody}}
+	}}
+	router := mux.NewRouter()
+	if err := v.Register(router, &controllers.Handler{}, &middleware.Auth{}); err == nil {
+		t.Fatal("Register() accepted a route without a policy")
+	}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		t.Errorf("Register() registered %s despite failing", path)
+		return nil
+	})
+}
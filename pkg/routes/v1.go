@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"github.com/J-Mihir/go-bookstore/pkg/controllers"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/openapi"
+)
+
+// V1 is the first versioned API, with the routes originally served at the
+// root.
+var V1 = Version{Name: "v1", Routes: v1Routes}
+
+// v1Routes is the route table of V1. Every route names who may call it and
+// the types its handler reads and writes; routes are matched in order.
+func v1Routes(h *controllers.Handler) []Route {
+	return []Route{
+		// Registration, login, sessions and passwords. Refresh and logout
+		// authenticate with the refresh token in the body, a password reset
+		// with the emailed token.
+		{"POST", "/register", h.RegisterUser, Public, Types[controllers.UserRequest, models.User]()},
+		{"POST", "/login", h.LoginUser, Public, Types[controllers.LoginRequest, controllers.TokenResponse]()},
+		{"POST", "/token/refresh", h.RefreshToken, Public, Types[controllers.RefreshRequest, controllers.TokenResponse]()},
+		{"POST", "/logout", h.Logout, Public, Types[controllers.RefreshRequest, None]()},
+		{"POST", "/password/forgot", h.ForgotPassword, Public, Types[controllers.ForgotPasswordRequest, None]()},
+		{"POST", "/password/reset", h.ResetPassword, Public, Types[controllers.ResetPasswordRequest, None]()},
+		{"PUT", "/me/password", h.ChangePassword, SignedIn, Types[controllers.ChangePasswordRequest, None]()},
+
+		// Books and their copies. /books/search comes before /books/{bookId}
+		// so "search" is not taken for an ID.
+		{"GET", "/books", h.GetBook, Public, Types[None, []models.Book]()},
+		{"GET", "/books/search", h.SearchBooks, Public, Types[None, []controllers.SearchResult]()},
+		{"GET", "/books/{bookId}", h.GetBookById, Public, Types[None, models.Book]()},
+		{"GET", "/books/{bookId}/copies", h.GetBookCopies, Public, Types[None, []models.BookCopy]()},
+		{"POST", "/books", h.CreateBook, Permission(models.PermBooksWrite), Types[controllers.BookRequest, models.Book]()},
+		{"POST", "/books/bulk", h.BulkBooks, Permission(models.PermBooksWrite), Types[controllers.BulkRequest, controllers.BulkResponse]()},
+		{"PUT", "/books/{bookId}", h.UpdateBook, Permission(models.PermBooksWrite), Types[models.Book, models.Book]()},
+		{"PATCH", "/books/{bookId}", h.PatchBook, Permission(models.PermBooksWrite), Types[models.Book, models.Book]()},
+		{"DELETE", "/books/{bookId}", h.DeleteBook, Permission(models.PermBooksWrite), Types[None, models.Book]()},
+		{"POST", "/books/{bookId}/copies", h.CreateBookCopy, Permission(models.PermBooksWrite), Types[controllers.BookCopyRequest, models.BookCopy]()},
+		{"PUT", "/books/{bookId}/copies/{copyId}", h.UpdateBookCopy, Permission(models.PermBooksWrite), Types[models.BookCopy, models.BookCopy]()},
+		{"DELETE", "/books/{bookId}/copies/{copyId}", h.DeleteBookCopy, Permission(models.PermBooksWrite), Types[None, None]()},
+
+		// Categories.
+		{"GET", "/categories", h.GetAllCategories, Public, Types[None, []models.Category]()},
+		{"GET", "/categories/{categoryId}", h.GetCategoryById, Public, Types[None, models.Category]()},
+		{"POST", "/categories", h.CreateCategory, Permission(models.PermCategoriesWrite), Types[controllers.CategoryRequest, models.Category]()},
+		{"PUT", "/categories/{categoryId}", h.UpdateCategory, Permission(models.PermCategoriesWrite), Types[models.Category, models.Category]()},
+		{"PATCH", "/categories/{categoryId}", h.PatchCategory, Permission(models.PermCategoriesWrite), Types[models.Category, models.Category]()},
+		{"DELETE", "/categories/{categoryId}", h.DeleteCategory, Permission(models.PermCategoriesWrite), Types[None, None]()},
+
+		// Users. Everyone can read and update their own record; the handlers
+		// require users:read or users:write for anyone else's.
+		{"GET", "/users", h.GetUser, Permission(models.PermUsersRead), Types[None, []models.User]()},
+		{"POST", "/users", h.CreateUser, Permission(models.PermUsersWrite), Types[controllers.UserRequest, models.User]()},
+		{"GET", "/users/{userId}", h.GetUserById, SignedIn, Types[None, models.User]()},
+		{"PUT", "/users/{userId}", h.UpdateUser, SignedIn, Types[models.User, models.User]()},
+		{"PATCH", "/users/{userId}", h.PatchUser, SignedIn, Types[models.User, models.User]()},
+		{"DELETE", "/users/{userId}", h.DeleteUser, Permission(models.PermUsersDelete), Types[None, models.User]()},
+
+		// Circulation. The handlers act for the caller, or with loans:manage
+		// or reservations:manage for the user named in the body.
+		{"POST", "/transactions/borrow", h.BorrowBook, Permission(models.PermLoansBorrow, models.PermLoansManage), Types[controllers.BorrowRequest, models.Transaction]()},
+		{"PUT", "/transactions/{transactionId}/return", h.ReturnBook, Permission(models.PermLoansBorrow, models.PermLoansManage), Types[None, models.Transaction]()},
+		{"PUT", "/transactions/{transactionId}/lost", h.MarkLoanLost, Permission(models.PermLoansManage), Types[None, models.Transaction]()},
+		{"POST", "/reservations", h.CreateReservation, Permission(models.PermReservationsCreate, models.PermReservationsManage), Types[controllers.ReservationRequest, models.Reservation]()},
+
+		// Soft-deleted records.
+		{"POST", "/trash/purge", h.PurgeTrash, Permission(models.PermTrashManage), Types[None, controllers.PurgeResponse]()},
+		{"GET", "/trash/{resource:books|users|categories}", h.GetTrash, Permission(models.PermTrashManage), Types[None, []any]()},
+		{"POST", "/trash/{resource:books|users|categories}/{id}/restore", h.RestoreFromTrash, Permission(models.PermTrashManage), Types[None, any]()},
+
+		// Roles and the permissions they grant.
+		{"GET", "/permissions", h.GetPermissions, Permission(models.PermRolesManage), Types[None, []models.Permission]()},
+		{"GET", "/roles", h.GetRoles, Permission(models.PermRolesManage), Types[None, []models.Role]()},
+		{"POST", "/roles", h.CreateRole, Permission(models.PermRolesManage), Types[controllers.RoleRequest, models.Role]()},
+		{"GET", "/roles/{roleId}", h.GetRoleById, Permission(models.PermRolesManage), Types[None, models.Role]()},
+		{"PUT", "/roles/{roleId}", h.UpdateRole, Permission(models.PermRolesManage), Types[models.Role, models.Role]()},
+		{"DELETE", "/roles/{roleId}", h.DeleteRole, Permission(models.PermRolesManage), Types[None, None]()},
+
+		// The OpenAPI document and Swagger UI.
+		{"GET", "/openapi.json", openapi.ServeDocument, Public, Types[None, map[string]any]()},
+		{"GET", "/docs", openapi.ServeUI, Public, Types[None, string]()},
+		{"GET", "/docs/{file:swagger-ui\\.css|swagger-ui-bundle\\.js}", openapi.ServeUIFile, Public, Types[None, string]()},
+	}
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"gorm.io/gorm"
+)
+
+// fullTextMatch scores books against the FULLTEXT index created by migration 0004.
+const fullTextMatch = "MATCH(name, author, publication, isbn, genre) AGAINST (? IN BOOLEAN MODE)"
+
+// minTokenSize is InnoDB's default innodb_ft_min_token_size; shorter words
+// are not indexed, so terms containing them are matched with LIKE instead.
+const minTokenSize = 3
+
+// fullTextBackend ranks books with MySQL's FULLTEXT index. Unqualified terms
+// go through MATCH ... AGAINST; field-qualified terms narrow the result with
+// LIKE on that column.
+type fullTextBackend struct {
+	db *gorm.DB
+}
+
+// NewFullTextBackend returns a Backend using MySQL FULLTEXT search on db.
+func NewFullTextBackend(db *gorm.DB) Backend {
+	return fullTextBackend{db: db}
+}
+
+func (b fullTextBackend) Search(q Query, offset, limit int) ([]Hit, int64, error) {
+	query := b.db.Model(&models.Book{})
+	var against []string
+	for _, term := range q.Terms {
+		if expr, ok := booleanTerm(term); ok {
+			against = append(against, expr)
+		} else {
+			query = likeTerm(query, term)
+		}
+	}
+
+	score, scoreArgs := "0", []any{}
+	if len(against) > 0 {
+		expr := strings.Join(against, " ")
+		query = query.Where(fullTextMatch, expr)
+		score, scoreArgs = fullTextMatch, []any{expr}
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := query.Select("id AS book_id, "+score+" AS score", scoreArgs...).Order("score DESC, name, id").Offset(offset)
+	if limit > 0 {
+		page = page.Limit(limit)
+	}
+	var hits []Hit
+	if err := page.Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// booleanTerm renders an unqualified term as a required boolean-mode
+// expression: a phrase in quotes, or each word as a prefix match. It reports
+// false when the term must be matched with LIKE instead.
+func booleanTerm(term Term) (string, bool) {
+	if term.Field != "" {
+		return "", false
+	}
+	words := strings.Fields(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, term.Text))
+	if len(words) == 0 {
+		return "", false
+	}
+	for _, w := range words {
+		if len(w) < minTokenSize {
+			return "", false
+		}
+	}
+
+	if term.Phrase {
+		return `+"` + strings.Join(words, " ") + `"`, true
+	}
+	for i, w := range words {
+		words[i] = "+" + w + "*"
+	}
+	return strings.Join(words, " "), true
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrEmptyQuery is returned when a query contains no search terms.
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Searchable book fields, usable as qualifiers such as author:tolkien.
+const (
+	FieldName        = "name"
+	FieldAuthor      = "author"
+	FieldPublication = "publication"
+	FieldISBN        = "isbn"
+	FieldGenre       = "genre"
+)
+
+// Fields lists the searchable fields in the order they are ranked.
+var Fields = []string{FieldName, FieldAuthor, FieldISBN, FieldGenre, FieldPublication}
+
+// fieldAliases maps every accepted qualifier to the field it searches.
+var fieldAliases = map[string]string{
+	FieldName:        FieldName,
+	"title":          FieldName,
+	FieldAuthor:      FieldAuthor,
+	FieldPublication: FieldPublication,
+	"publisher":      FieldPublication,
+	FieldISBN:        FieldISBN,
+	FieldGenre:       FieldGenre,
+}
+
+// Term is one word or quoted phrase of a query.
+type Term struct {
+	// Field restricts the term to one field; empty searches every field.
+	Field string
+	// Text is the lower-cased word or phrase.
+	Text   string
+	Phrase bool
+}
+
+// Query is a parsed search. A book matches when it matches every term.
+type Query struct {
+	Terms []Term
+}
+
+// Parse reads a query such as `hobbit author:tolkien "middle earth"`.
+// Words and "quoted phrases" may be prefixed with a field qualifier;
+// an unknown qualifier is searched as part of the word.
+func Parse(s string) (Query, error) {
+	var q Query
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		var term Term
+		if i := strings.IndexByte(rest, ':'); i > 0 && !strings.ContainsFunc(rest[:i], unicode.IsSpace) {
+			if field, ok := fieldAliases[strings.ToLower(rest[:i])]; ok {
+				term.Field = field
+				rest = rest[i+1:]
+			}
+		}
+
+		var text string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				text, rest = rest[1:], ""
+			} else {
+				text, rest = rest[1:end+1], rest[end+2:]
+			}
+			term.Phrase = true
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			text, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		term.Text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+		if term.Text != "" {
+			q.Terms = append(q.Terms, term)
+		}
+	}
+	if len(q.Terms) == 0 {
+		return q, ErrEmptyQuery
+	}
+	return q, nil
+}
+
+// fields returns the fields the term is searched in.
+func (t Term) fields() []string {
+	if t.Field != "" {
+		return []string{t.Field}
+	}
+	return Fields
+}
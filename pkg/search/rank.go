@@ -0,0 +1,90 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+)
+
+// fieldWeights rank a match in the title or author above one in the genre or publisher.
+var fieldWeights = map[string]float64{
+	FieldName:        3,
+	FieldAuthor:      3,
+	FieldISBN:        4,
+	FieldGenre:       2,
+	FieldPublication: 1,
+}
+
+// phraseBoost favours phrase matches, which are more specific than single words.
+const phraseBoost = 1.5
+
+// fieldValue returns the lower-cased text of a searchable book field.
+// ISBNs are compared without hyphens or spaces.
+func fieldValue(b *models.Book, field string) string {
+	switch field {
+	case FieldName:
+		return strings.ToLower(b.Name)
+	case FieldAuthor:
+		return strings.ToLower(b.Author)
+	case FieldPublication:
+		return strings.ToLower(b.Publication)
+	case FieldISBN:
+		return normalizeISBN(b.ISBN)
+	case FieldGenre:
+		return strings.ToLower(b.Genre)
+	}
+	return ""
+}
+
+func normalizeISBN(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(s))
+}
+
+// Score ranks the book against q. It reports false unless every term matches
+// at least one of its fields. Within a field, a whole-field match scores above
+// a match at the start of a word, which scores above any other substring.
+func Score(b *models.Book, q Query) (float64, bool) {
+	var total float64
+	for _, term := range q.Terms {
+		var termScore float64
+		for _, field := range term.fields() {
+			text := term.Text
+			if field == FieldISBN {
+				text = normalizeISBN(text)
+			}
+			termScore += matchScore(fieldValue(b, field), text) * fieldWeights[field]
+		}
+		if termScore == 0 {
+			return 0, false
+		}
+		if term.Phrase {
+			termScore *= phraseBoost
+		}
+		total += termScore
+	}
+	return total, true
+}
+
+// matchScore grades how well text occurs in value: 0 when it does not occur.
+func matchScore(value, text string) float64 {
+	i := strings.Index(value, text)
+	switch {
+	case text == "" || i < 0:
+		return 0
+	case value == text:
+		return 3
+	case startsWord(value, i):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// startsWord reports whether position i of s is the start of a word.
+func startsWord(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	c := s[i-1]
+	return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9')
+}
@@ -0,0 +1,23 @@
+package search
+
+import "github.com/J-Mihir/go-bookstore/pkg/repository"
+
+// scanBackend ranks the whole catalog in process. It works with any Store,
+// including the in-memory one used in tests, and suits small catalogs.
+type scanBackend struct {
+	store repository.Store
+}
+
+// NewScanBackend returns a Backend that reads every book from store.
+func NewScanBackend(store repository.Store) Backend {
+	return scanBackend{store: store}
+}
+
+func (b scanBackend) Search(q Query, offset, limit int) ([]Hit, int64, error) {
+	books, _, err := b.store.Books().List(repository.ListQuery{})
+	if err != nil {
+		return nil, 0, err
+	}
+	hits, total := rank(books, q, offset, limit)
+	return hits, total, nil
+}
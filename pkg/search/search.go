@@ -0,0 +1,87 @@
+// Package search implements catalog search over books. Queries are parsed
+// once and answered by a Backend chosen for the database in use: a portable
+// SQL backend, MySQL FULLTEXT, or an in-process scan of any repository.Store.
+package search
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/J-Mihir/go-bookstore/pkg/config"
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// Hit is a matching book and its relevance; higher scores are better matches.
+type Hit struct {
+	BookID uint
+	Score  float64
+}
+
+// Backend answers search queries.
+type Backend interface {
+	// Search returns the hits from offset up to limit, best match first, and
+	// the total number of matching books. A limit of zero returns every hit.
+	Search(q Query, offset, limit int) ([]Hit, int64, error)
+}
+
+// ForDatabase returns the configured backend for db. config.SearchAuto picks
+// FULLTEXT on MySQL and the portable SQL backend elsewhere.
+func ForDatabase(db *gorm.DB, cfg config.SearchConfig) (Backend, error) {
+	switch cfg.Backend {
+	case config.SearchAuto, "":
+		if db.Dialector.Name() == "mysql" {
+			return NewFullTextBackend(db), nil
+		}
+		return NewSQLBackend(db), nil
+	case config.SearchSQL:
+		return NewSQLBackend(db), nil
+	case config.SearchFullText:
+		if db.Dialector.Name() != "mysql" {
+			return nil, fmt.Errorf("the %s search backend requires MySQL", config.SearchFullText)
+		}
+		return NewFullTextBackend(db), nil
+	case config.SearchScan:
+		return NewScanBackend(repository.NewGormStore(db)), nil
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Backend)
+	}
+}
+
+// rank scores books against q and returns the requested page of hits. Ties
+// are ordered by name, then ID, so pages are stable.
+func rank(books []models.Book, q Query, offset, limit int) ([]Hit, int64) {
+	type scored struct {
+		hit  Hit
+		name string
+	}
+	var matches []scored
+	for i := range books {
+		if score, ok := Score(&books[i], q); ok {
+			matches = append(matches, scored{Hit{books[i].ID, score}, books[i].Name})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.hit.Score != b.hit.Score {
+			return a.hit.Score > b.hit.Score
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.hit.BookID < b.hit.BookID
+	})
+
+	total := int64(len(matches))
+	start := min(offset, len(matches))
+	end := len(matches)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	hits := make([]Hit, 0, end-start)
+	for _, m := range matches[start:end] {
+		hits = append(hits, m.hit)
+	}
+	return hits, total
+}
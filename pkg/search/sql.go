@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/models"
+	"github.com/J-Mihir/go-bookstore/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// sqlColumns are the expressions compared with each field's search text;
+// they match fieldValue so the database and Score agree on what matches.
+var sqlColumns = map[string]string{
+	FieldName:        "LOWER(name)",
+	FieldAuthor:      "LOWER(author)",
+	FieldPublication: "LOWER(publication)",
+	FieldISBN:        "REPLACE(REPLACE(LOWER(isbn), '-', ''), ' ', '')",
+	FieldGenre:       "LOWER(genre)",
+}
+
+// sqlBackend matches and ranks books with LIKE conditions in the database, so
+// only the requested page is read. It runs on SQLite, PostgreSQL and MySQL.
+type sqlBackend struct {
+	db *gorm.DB
+}
+
+// NewSQLBackend returns a Backend that searches db with portable SQL.
+func NewSQLBackend(db *gorm.DB) Backend {
+	return sqlBackend{db: db}
+}
+
+func (b sqlBackend) Search(q Query, offset, limit int) ([]Hit, int64, error) {
+	query := b.db.Model(&models.Book{})
+	var scores []string
+	var scoreArgs []any
+	for _, term := range q.Terms {
+		query = likeTerm(query, term)
+		expr, args := termScore(term)
+		scores = append(scores, expr)
+		scoreArgs = append(scoreArgs, args...)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	score := "0"
+	if len(scores) > 0 {
+		score = strings.Join(scores, " + ")
+	}
+	page := query.Select("id AS book_id, "+score+" AS score", scoreArgs...).Order("score DESC, name, id").Offset(offset)
+	if limit > 0 {
+		page = page.Limit(limit)
+	}
+	var hits []Hit
+	if err := page.Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// termScore renders Score's grading of one term as SQL: per field, a whole
+// field match scores 3, a match at the start of the field or after a space 2,
+// and any other match 1, weighted and summed. Score also treats punctuation
+// as starting a word, so the two can differ slightly on such titles.
+func termScore(term Term) (string, []any) {
+	var parts []string
+	var args []any
+	for _, field := range term.fields() {
+		text := term.Text
+		if field == FieldISBN {
+			text = normalizeISBN(text)
+		}
+		column, escaped := sqlColumns[field], repository.EscapeLike(text)
+		parts = append(parts, fmt.Sprintf("CASE WHEN %[1]s = ? THEN 3"+
+			" WHEN %[1]s LIKE ? ESCAPE '!' OR %[1]s LIKE ? ESCAPE '!' THEN 2"+
+			" WHEN %[1]s LIKE ? ESCAPE '!' THEN 1 ELSE 0 END * %[2]g", column, fieldWeights[field]))
+		args = append(args, text, escaped+"%", "% "+escaped+"%", "%"+escaped+"%")
+	}
+	expr := "(" + strings.Join(parts, " + ") + ")"
+	if term.Phrase {
+		expr = fmt.Sprintf("%s * %g", expr, phraseBoost)
+	}
+	return expr, args
+}
+
+// likeTerm restricts query to books where term occurs in one of its fields.
+func likeTerm(query *gorm.DB, term Term) *gorm.DB {
+	var conditions []string
+	var args []any
+	for _, field := range term.fields() {
+		text := term.Text
+		if field == FieldISBN {
+			text = normalizeISBN(text)
+		}
+		conditions = append(conditions, sqlColumns[field]+" LIKE ? ESCAPE '!'")
+		args = append(args, "%"+repository.EscapeLike(text)+"%")
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
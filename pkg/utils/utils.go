@@ -1,15 +1,128 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/mergepatch"
 )
 
-func ParseBody(r *http.Request, x interface{}) {
-	if body, err := io.ReadAll(r.Body); err == nil {
-		if err := json.Unmarshal([]byte(body), x); err != nil {
-			return
+// MergePatchType is the media type of JSON merge patch bodies (RFC 7396).
+const MergePatchType = "application/merge-patch+json"
+
+// ParseBody strictly decodes the JSON request body into x. Malformed JSON,
+// unknown fields, values of the wrong type, trailing data and bodies over the
+// limit set by middleware.LimitBody are reported as *apierror.Error.
+func ParseBody(r *http.Request, x interface{}) error {
+	return decodeStrict(r.Body, x)
+}
+
+// ParseMergePatch applies the JSON merge patch in the request body to the JSON
+// form of current and strictly decodes the result into x, which should be a
+// new zero value: members the patch sets to null are removed and decode to
+// their zero value. Errors are reported as by ParseBody. The body may be sent
+// as application/merge-patch+json or application/json.
+func ParseMergePatch(r *http.Request, current, x interface{}) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != MergePatchType && mediaType != "application/json") {
+			return apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMediaType,
+				"PATCH bodies must be sent as "+MergePatchType)
 		}
 	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return decodeError(err)
+	}
+	// Check the patch on its own first so its syntax errors are reported as such.
+	var v interface{}
+	if err := decodeStrict(bytes.NewReader(patch), &v); err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return apierror.Internal(err)
+	}
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return apierror.Internal(err)
+	}
+	return decodeStrict(bytes.NewReader(merged), x)
+}
+
+func decodeStrict(body io.Reader, x interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(x); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err != nil {
+			if e := decodeError(err); e.Code == apierror.CodeBodyTooLarge {
+				return e
+			}
+		}
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody, "Request body must contain a single JSON value")
+	}
+	return nil
+}
+
+// decodeError explains why the body could not be decoded.
+func decodeError(err error) *apierror.Error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge,
+			fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody, "Request body must not be empty")
+	case errors.As(err, &syntaxErr):
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody,
+			fmt.Sprintf("Request body contains malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody, "Request body contains malformed JSON")
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody, "Request body must be a JSON object")
+		}
+		return apierror.Validation(apierror.FieldError{
+			Field:   field,
+			Code:    "invalid_type",
+			Message: fmt.Sprintf("%s must be a JSON %s", field, jsonType(typeErr.Type.Kind().String())),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apierror.New(http.StatusBadRequest, apierror.CodeUnknownField, fmt.Sprintf("Unknown field %q", field)).
+			WithDetails(apierror.FieldError{Field: field, Code: "unknown_field", Message: field + " is not a recognised field"})
+	default:
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidBody, "Request body could not be decoded")
+	}
+}
+
+// jsonType names the JSON type expected for a Go kind.
+func jsonType(kind string) string {
+	switch {
+	case kind == "string":
+		return "string"
+	case kind == "bool":
+		return "boolean"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "slice", kind == "array":
+		return "array"
+	default:
+		return "object"
+	}
 }
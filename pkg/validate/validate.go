@@ -0,0 +1,197 @@
+// Package validate checks structs against declarative rules in their
+// `validate` tags and reports every failing field at once:
+//
+//	Email string `json:"email" validate:"required,email"`
+//
+// Rules are separated by commas:
+//
+//	required     the value is not empty (zero, blank string or nil)
+//	email        a plain address such as reader@example.com
+//	isbn         a valid ISBN-10 or ISBN-13, hyphens and spaces allowed
+//	min=N        numbers are at least N; strings have at least N characters
+//	oneof=a b c  the value is one of the listed words
+//
+// Rules other than required are skipped for empty values, so optional fields
+// are only checked when present. Fields are reported by their JSON names, and
+// embedded structs are checked as part of their parent.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+)
+
+// Struct validates v, a struct or pointer to a struct. It returns nil when
+// every rule passes and otherwise a 422 *apierror.Error listing each failure.
+func Struct(v any) error {
+	if details := Fields(v); len(details) > 0 {
+		return apierror.Validation(details...)
+	}
+	return nil
+}
+
+// Fields returns the field errors of v without wrapping them in an API error.
+func Fields(v any) []apierror.FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+	var details []apierror.FieldError
+	checkStruct(rv, &details)
+	return details
+}
+
+func checkStruct(rv reflect.Value, details *[]apierror.FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			checkStruct(value, details)
+			continue
+		}
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		name := jsonName(field)
+		for _, rule := range strings.Split(tag, ",") {
+			if fe := checkRule(name, strings.TrimSpace(rule), value); fe != nil {
+				*details = append(*details, *fe)
+				// Later rules add nothing once a field is missing or malformed.
+				break
+			}
+		}
+	}
+}
+
+// jsonName is the name clients use for the field.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func checkRule(name, rule string, value reflect.Value) *apierror.FieldError {
+	rule, arg, _ := strings.Cut(rule, "=")
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			if rule == "required" {
+				return fieldError(name, "required", name+" is required")
+			}
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if rule == "required" {
+		if isEmpty(value) {
+			return fieldError(name, "required", name+" is required")
+		}
+		return nil
+	}
+	if isEmpty(value) {
+		return nil
+	}
+
+	switch rule {
+	case "email":
+		s := value.String()
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return fieldError(name, "invalid_email", name+" must be a valid email address")
+		}
+	case "isbn":
+		if !ValidISBN(value.String()) {
+			return fieldError(name, "invalid_isbn", name+" must be a valid ISBN-10 or ISBN-13")
+		}
+	case "min":
+		min, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad min rule %q on %s", arg, name))
+		}
+		if value.Kind() == reflect.String {
+			if float64(utf8.RuneCountInString(value.String())) < min {
+				return fieldError(name, "too_short", fmt.Sprintf("%s must be at least %s characters", name, arg))
+			}
+		} else if number(value) < min {
+			return fieldError(name, "too_small", fmt.Sprintf("%s must be at least %s", name, arg))
+		}
+	case "oneof":
+		allowed := strings.Fields(arg)
+		if !slices.Contains(allowed, fmt.Sprint(value.Interface())) {
+			return fieldError(name, "invalid_choice", fmt.Sprintf("%s must be one of: %s", name, strings.Join(allowed, ", ")))
+		}
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q on %s", rule, name))
+	}
+	return nil
+}
+
+func fieldError(field, code, message string) *apierror.FieldError {
+	return &apierror.FieldError{Field: field, Code: code, Message: message}
+}
+
+func isEmpty(value reflect.Value) bool {
+	if value.Kind() == reflect.String {
+		return strings.TrimSpace(value.String()) == ""
+	}
+	return value.IsZero()
+}
+
+func number(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	panic(fmt.Sprintf("validate: min rule on non-numeric %s", value.Type()))
+}
+
+// ValidISBN reports whether s is an ISBN-10 or ISBN-13 with a correct check
+// digit. Hyphens and spaces are ignored.
+func ValidISBN(s string) bool {
+	digits := strings.NewReplacer("-", "", " ", "").Replace(s)
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			var d int
+			switch {
+			case c >= '0' && c <= '9':
+				d = int(c - '0')
+			case (c == 'X' || c == 'x') && i == 9:
+				d = 10
+			default:
+				return false
+			}
+			sum += (10 - i) * d
+		}
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, c := range digits {
+			if c < '0' || c > '9' {
+				return false
+			}
+			d := int(c - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+			sum += d
+		}
+		return sum%10 == 0
+	}
+	return false
+}
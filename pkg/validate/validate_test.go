@@ -0,0 +1,121 @@
+package validate_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/J-Mihir/go-bookstore/pkg/apierror"
+	"github.com/J-Mihir/go-bookstore/pkg/validate"
+)
+
+func TestValidISBN(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want bool
+	}{
+		{"0306406152", true},
+		{"0-306-40615-2", true},
+		{"0 306 40615 2", true},
+		{"080442957X", true},
+		{"080442957x", true},
+		{"9780306406157", true},
+		{"978-0-306-40615-7", true},
+		{"0306406153", false},     // wrong check digit
+		{"9780306406158", false},  // wrong check digit
+		{"X306406152", false},     // X is only a check digit
+		{"978030640615X", false},  // ISBN-13 has no X
+		{"030640615", false},      // too short
+		{"97803064061570", false}, // too long
+		{"03064O6152", false},     // letter O
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validate.ValidISBN(tt.isbn); got != tt.want {
+			t.Errorf("ValidISBN(%q) = %v, want %v", tt.isbn, got, tt.want)
+		}
+	}
+}
+
+type Base struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type member struct {
+	Base
+	Email  string  `json:"email" validate:"required,email"`
+	ISBN   string  `json:"isbn" validate:"isbn"`
+	Age    int     `json:"age" validate:"min=18"`
+	Code   string  `json:"code" validate:"min=3"`
+	Plan   string  `json:"plan" validate:"oneof=basic premium"`
+	Note   *string `json:"note" validate:"required"`
+	NoJSON string  `validate:"required"`
+	hidden string  `validate:"required"` // unexported, so never checked
+}
+
+func TestFields(t *testing.T) {
+	note := "hi"
+	tests := []struct {
+		name string
+		v    member
+		want []apierror.FieldError
+	}{
+		{
+			name: "valid",
+			v:    member{Base: Base{"Ada"}, Email: "ada@example.com", ISBN: "0-306-40615-2", Age: 18, Code: "abc", Plan: "basic", Note: &note, NoJSON: "x"},
+		},
+		{
+			name: "optional rules skip empty values",
+			v:    member{Base: Base{"Ada"}, Email: "ada@example.com", Note: &note, NoJSON: "x"},
+		},
+		{
+			name: "missing",
+			v:    member{Base: Base{"  "}},
+			want: []apierror.FieldError{
+				{Field: "name", Code: "required", Message: "name is required"},
+				{Field: "email", Code: "required", Message: "email is required"},
+				{Field: "note", Code: "required", Message: "note is required"},
+				{Field: "NoJSON", Code: "required", Message: "NoJSON is required"},
+			},
+		},
+		{
+			name: "malformed",
+			v:    member{Base: Base{"Ada"}, Email: "Ada <ada@example.com>", ISBN: "0306406153", Age: 17, Code: "ab", Plan: "gold", Note: &note, NoJSON: "x"},
+			want: []apierror.FieldError{
+				{Field: "email", Code: "invalid_email", Message: "email must be a valid email address"},
+				{Field: "isbn", Code: "invalid_isbn", Message: "isbn must be a valid ISBN-10 or ISBN-13"},
+				{Field: "age", Code: "too_small", Message: "age must be at least 18"},
+				{Field: "code", Code: "too_short", Message: "code must be at least 3 characters"},
+				{Field: "plan", Code: "invalid_choice", Message: "plan must be one of: basic, premium"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validate.Fields(&tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fields() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructReportsUnprocessableEntity(t *testing.T) {
+	err := validate.Struct(member{})
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("Struct() = %v, want a 422 *apierror.Error", err)
+	}
+	if err := validate.Struct(Base{"Ada"}); err != nil {
+		t.Errorf("Struct() = %v, want nil", err)
+	}
+}
+
+func TestMinCountsCharacters(t *testing.T) {
+	v := struct {
+		Code string `json:"code" validate:"min=3"`
+	}{"äöü"}
+	if got := validate.Fields(v); got != nil {
+		t.Errorf("Fields() = %+v, want nil for three multi-byte characters", got)
+	}
+}